@@ -0,0 +1,154 @@
+package api_integration_test
+
+import (
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("actor isolation between application identities", func() {
+	var (
+		appA, appB, uaaUser *CredhubClient
+		credentialName      string
+		written             Credential
+	)
+
+	BeforeEach(func() {
+		config, err = LoadConfig(IntegrationConfig...)
+		Expect(err).NotTo(HaveOccurred())
+
+		appA, err = createMtlsClient(client_certs.ForApp(client_certs.NewGuid()))
+		Expect(err).NotTo(HaveOccurred())
+		appB, err = createMtlsClient(client_certs.ForApp(client_certs.NewGuid()))
+		Expect(err).NotTo(HaveOccurred())
+		uaaUser, err = NewTokenClient(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		credentialName = "/" + GenerateUniqueCredentialName()
+	})
+
+	Describe("a credential written by an application", func() {
+		BeforeEach(func() {
+			written, err = appA.Set(credentialName, "password", "app-a-password", true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("can be read, updated and deleted by that application", func() {
+			_, err := appA.GetByName(credentialName)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = appA.GetById(written.Id)
+			Expect(err).NotTo(HaveOccurred())
+
+			updated, err := appA.Set(credentialName, "password", "new-app-a-password", true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.AsPassword().Value).To(Equal("new-app-a-password"))
+
+			Expect(appA.Delete(credentialName)).To(Succeed())
+		})
+
+		It("cannot be read by another application", func() {
+			_, err := appB.GetByName(credentialName)
+			expectNoAccess(err)
+			_, err = appB.GetById(written.Id)
+			expectNoAccess(err)
+		})
+
+		It("cannot be found by another application", func() {
+			By("finding by path")
+			found, err := appA.FindByPath(path.Dir(credentialName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).To(ContainElement(credentialName))
+			found, err = appB.FindByPath(path.Dir(credentialName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).NotTo(ContainElement(credentialName))
+
+			By("finding by name-like")
+			found, err = appA.FindByNameLike(path.Base(credentialName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).To(ContainElement(credentialName))
+			found, err = appB.FindByNameLike(path.Base(credentialName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).NotTo(ContainElement(credentialName))
+		})
+
+		It("cannot be overwritten by another application", func() {
+			_, err := appB.Set(credentialName, "password", "app-b-password", true)
+			expectNoAccess(err)
+			_, err = appB.Generate(credentialName, "password", nil, true)
+			expectNoAccess(err)
+
+			current, err := appA.GetByName(credentialName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current.AsPassword().Value).To(Equal("app-a-password"))
+		})
+
+		It("cannot be deleted by another application", func() {
+			expectNoAccess(appB.Delete(credentialName))
+
+			_, err := appA.GetByName(credentialName)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("cannot be used by a UAA user", func() {
+			_, err := uaaUser.GetByName(credentialName)
+			expectNoAccess(err)
+			_, err = uaaUser.Set(credentialName, "password", "uaa-password", true)
+			expectNoAccess(err)
+			expectNoAccess(uaaUser.Delete(credentialName))
+		})
+	})
+
+	Describe("a credential written by a UAA user", func() {
+		BeforeEach(func() {
+			written, err = uaaUser.Set(credentialName, "password", "uaa-password", true)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("cannot be used by an application", func() {
+			_, err := appA.GetByName(credentialName)
+			expectNoAccess(err)
+			_, err = appA.GetById(written.Id)
+			expectNoAccess(err)
+			_, err = appA.Set(credentialName, "password", "app-a-password", true)
+			expectNoAccess(err)
+			expectNoAccess(appA.Delete(credentialName))
+
+			current, err := uaaUser.GetByName(credentialName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current.AsPassword().Value).To(Equal("uaa-password"))
+		})
+	})
+
+	It("lets each application use only the credentials it wrote", func() {
+		nameA := "/" + GenerateNestedCredentialName("app-a")
+		nameB := "/" + GenerateNestedCredentialName("app-b")
+
+		_, err := appA.Set(nameA, "value", "written by app a", true)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = appB.Set(nameB, "value", "written by app b", true)
+		Expect(err).NotTo(HaveOccurred())
+
+		ownA, err := appA.GetByName(nameA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ownA.AsValue().Value).To(Equal("written by app a"))
+		ownB, err := appB.GetByName(nameB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ownB.AsValue().Value).To(Equal("written by app b"))
+
+		_, err = appA.GetByName(nameB)
+		expectNoAccess(err)
+		_, err = appB.GetByName(nameA)
+		expectNoAccess(err)
+	})
+})
+
+// expectNoAccess checks for the error CredHub gives both for credentials that
+// do not exist and for ones the actor has no permission to use, so that
+// neither reveals which credentials exist.
+func expectNoAccess(err error) {
+	Expect(err).To(BeAssignableToTypeOf(&ApiError{}))
+	Expect(err.Error()).To(ContainSubstring("does not exist or you do not have sufficient authorization"))
+}
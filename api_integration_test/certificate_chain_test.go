@@ -0,0 +1,35 @@
+package api_integration_test
+
+import (
+	"fmt"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("generated certificate chains", func() {
+	var client *CredhubClient
+
+	BeforeEach(func() {
+		config, err = LoadConfig(MtlsConfig...)
+		Expect(err).NotTo(HaveOccurred())
+		client, err = createMtlsClient(trustedFixture())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	for _, depth := range ChainDepths {
+		depth := depth
+
+		It(fmt.Sprintf("verifies a leaf signed through %d intermediate CAs up to the root", depth), func() {
+			chain := []CertificateCredential{}
+			for _, link := range ChainLinks("/"+GenerateUniqueCredentialName(), depth) {
+				generated, err := client.Generate(link.Name, "certificate", link.Parameters(), true)
+				Expect(err).NotTo(HaveOccurred())
+				chain = append(chain, generated.AsCertificate())
+			}
+
+			ExpectChainVerifies(chain)
+		})
+	}
+})
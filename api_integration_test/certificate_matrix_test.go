@@ -0,0 +1,48 @@
+package api_integration_test
+
+import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("generating certificates with every supported parameter", func() {
+	var client *CredhubClient
+
+	BeforeEach(func() {
+		config, err = LoadConfig(MtlsConfig...)
+		Expect(err).NotTo(HaveOccurred())
+		client, err = createMtlsClient(trustedFixture())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	for _, certificateCase := range CertificateCases {
+		certificateCase := certificateCase
+
+		It("generates a certificate signed by a CA with "+certificateCase.Description, func() {
+			caName := "/" + GenerateNestedCredentialName("ca")
+			generated, err := client.Generate(caName, "certificate", map[string]interface{}{
+				"common_name": MatrixCaCommonName,
+				"is_ca":       true,
+			}, true)
+			Expect(err).NotTo(HaveOccurred())
+			ca := generated.AsCertificate()
+
+			parameters := certificateCase.Parameters()
+			parameters["ca"] = caName
+			generated, err = client.Generate("/"+GenerateUniqueCredentialName(), "certificate", parameters, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ExpectCertificateMatches(generated.AsCertificate(), certificateCase, &ca)
+		})
+
+		It("generates a self-signed certificate with "+certificateCase.Description, func() {
+			parameters := certificateCase.Parameters()
+			parameters["self_sign"] = true
+			generated, err := client.Generate("/"+GenerateUniqueCredentialName(), "certificate", parameters, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			ExpectCertificateMatches(generated.AsCertificate(), certificateCase, nil)
+		})
+	}
+})
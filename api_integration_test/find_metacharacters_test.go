@@ -0,0 +1,47 @@
+package api_integration_test
+
+import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("finding credentials by names containing LIKE metacharacters", func() {
+	var (
+		client *CredhubClient
+		base   string
+	)
+
+	BeforeEach(func() {
+		config, err = LoadConfig(MtlsConfig...)
+		Expect(err).NotTo(HaveOccurred())
+		client, err = createMtlsClient(trustedFixture())
+		Expect(err).NotTo(HaveOccurred())
+
+		base = "/" + GenerateNestedCredentialName("like")
+		for _, name := range LikeMetacharacterNames {
+			_, err := client.Set(base+"/"+name, "value", "metacharacters", true)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	for _, query := range NameLikeQueries {
+		query := query
+
+		It("finds by name only the literal matches for "+query.Description, func() {
+			found, err := client.FindByNameLike(base + "/" + query.Query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).To(ConsistOf(query.MatchesUnder(base)))
+		})
+	}
+
+	for _, query := range PathQueries {
+		query := query
+
+		It("finds by path only the literal matches for "+query.Description, func() {
+			found, err := client.FindByPath(base + "/" + query.Query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(FoundNames(found)).To(ConsistOf(query.MatchesUnder(base)))
+		})
+	}
+})
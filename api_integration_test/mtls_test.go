@@ -1,131 +1,171 @@
 package api_integration_test
 
 import (
-	"bytes"
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"os"
 	"path"
 	"testing"
 
 	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"fmt"
 )
 
 var (
 	config Config
 	err    error
+
+	// appGuid identifies this node's trusted client. CredHub only lets the
+	// identity that created a credential use it, so clients that share
+	// credentials must share it.
+	appGuid = client_certs.NewGuid()
 )
 
 var _ = Describe("mutual TLS authentication", func() {
 
 	Describe("with a certificate signed by a trusted CA	", func() {
 		BeforeEach(func() {
-			config, err = LoadConfig()
+			config, err = LoadConfig(MtlsConfig...)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("allows the client to hit an authenticated endpoint", func() {
-			postData := map[string]string{
-				"name": "mtlstest",
-				"type": "password",
-			}
-			result, err := mtlsPost(
-				config.ApiUrl+"/api/v1/data",
-				postData,
-				"server_ca_cert.pem",
-				"client.pem",
-				"client_key.pem")
+			result, err := mtlsGenerate(client_certs.Valid())
 
 			Expect(err).To(BeNil())
-			Expect(result).To(MatchRegexp(`"type":"password"`))
+			Expect(result.Type).To(Equal("password"))
+		})
+
+		forEveryVerb("lets the client", trustedFixture, func(err error) {
+			Expect(err).NotTo(HaveOccurred())
 		})
 	})
 
 	Describe("with an expired certificate", func() {
 		BeforeEach(func() {
-			config, err = LoadConfig()
+			config, err = LoadConfig(MtlsConfig...)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("prevents the client from hitting an authenticated endpoint", func() {
-			postData := map[string]string{
-				"name": "mtlstest",
-				"type": "password",
-			}
-			result, err := mtlsPost(
-				config.ApiUrl+"/api/v1/data",
-				postData,
-				"server_ca_cert.pem",
-				"expired.pem",
-				"expired_key.pem")
+			result, err := mtlsGenerate(client_certs.Expired())
 
 			Expect(err.Error()).To(ContainSubstring("unknown certificate"))
-			Expect(result).To(BeEmpty())
+			Expect(result).To(BeZero())
 		})
+
+		forEveryVerb("does not let the client", client_certs.Expired, expectRejectedDuringHandshake)
 	})
 
 	Describe("with a self-signed certificate", func() {
 		BeforeEach(func() {
-			config, err = LoadConfig()
+			config, err = LoadConfig(MtlsConfig...)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("prevents the client from hitting an authenticated endpoint", func() {
-			postData := map[string]string{
-				"name": "mtlstest",
-				"type": "password",
-			}
-			result, err := mtlsPost(
-				config.ApiUrl+"/api/v1/data",
-				postData,
-				"server_ca_cert.pem",
-				"selfsigned.pem",
-				"selfsigned_key.pem")
+			_, err := mtlsGenerate(client_certs.SelfSigned())
 
 			// golang doesn't seem to send self-signed certs
 			// server.ssl.client-auth=want (https://tools.ietf.org/html/rfc5246#section-7.4.4)
 			// That is why, we are asserting on OAuth authorization failure here.
-			Expect(err).To(BeNil())
-			Expect(result).To(MatchRegexp(".*Full authentication is required to access this resource"))
+			Expect(err).To(BeAssignableToTypeOf(&ApiError{}))
+			Expect(err.Error()).To(MatchRegexp(".*Full authentication is required to access this resource"))
 		})
+
+		forEveryVerb("does not let the client", client_certs.SelfSigned, expectUnauthenticated)
 	})
 
 	Describe("with a certificate signed by an unknown CA", func() {
 		BeforeEach(func() {
-			config, err = LoadConfig()
+			config, err = LoadConfig(MtlsConfig...)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("prevents the client from hitting an authenticated endpoint", func() {
-			postData := map[string]string{
-				"name": "mtlstest",
-				"type": "password",
-			}
-			result, err := mtlsPost(
-				config.ApiUrl+"/api/v1/data",
-				postData,
-				"server_ca_cert.pem",
-				"unknown.pem",
-				"unknown_key.pem")
+			unknownCA, err := client_certs.UnknownCA()
+			Expect(err).NotTo(HaveOccurred())
 
+			_, err = mtlsGenerate(unknownCA)
 
 			// Okay, so golang 1.7.x **sometimes** doesn't seem to send certs that the server won't accept...
-			// Here we assert that, if there was an error, it should be the server rejecting the cert, and
-			// if there wasn't an error, the server told us to go away because we didn't send an auth token or cert.
-			if err != nil {
+			// Here we assert that, if the server rejected the cert during the handshake, it said so, and
+			// otherwise the server told us to go away because we didn't send an auth token or cert.
+			Expect(err).To(HaveOccurred())
+			if _, ok := err.(*ApiError); !ok {
 				Expect(err.Error()).To(ContainSubstring("unknown certificate"))
 			} else {
-				Expect(result).To(MatchRegexp(".*Full authentication is required to access this resource"))
+				Expect(err.Error()).To(MatchRegexp(".*Full authentication is required to access this resource"))
+			}
+		})
+
+		forEveryVerb("does not let the client", unknownCA, func(err error) {
+			Expect(err).To(HaveOccurred())
+			if _, ok := err.(*ApiError); !ok {
+				expectRejectedDuringHandshake(err)
+			} else {
+				expectUnauthenticated(err)
 			}
 		})
 	})
+
+	Describe("with a certificate that is not valid yet", func() {
+		BeforeEach(func() {
+			config, err = LoadConfig(MtlsConfig...)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("prevents the client from hitting an authenticated endpoint", func() {
+			result, err := mtlsGenerate(client_certs.NotYetValid())
+
+			Expect(err.Error()).To(ContainSubstring("unknown certificate"))
+			Expect(result).To(BeZero())
+		})
+	})
+
+	Describe("with a certificate that may not be used for client authentication", func() {
+		BeforeEach(func() {
+			config, err = LoadConfig(MtlsConfig...)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("prevents the client from hitting an authenticated endpoint", func() {
+			result, err := mtlsGenerate(client_certs.WrongExtKeyUsage())
+
+			Expect(err.Error()).To(ContainSubstring("unknown certificate"))
+			Expect(result).To(BeZero())
+		})
+	})
+
+	Describe("with a certificate with an ECDSA key", func() {
+		BeforeEach(func() {
+			config, err = LoadConfig(MtlsConfig...)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("prevents the client from hitting an authenticated endpoint", func() {
+			result, err := mtlsGenerate(client_certs.WrongKeyType())
+
+			Expect(err.Error()).To(ContainSubstring("unknown certificate"))
+			Expect(result).To(BeZero())
+		})
+
+		forEveryVerb("does not let the client", client_certs.WrongKeyType, expectRejectedDuringHandshake)
+	})
+
+	Describe("with a certificate that does not identify an application", func() {
+		BeforeEach(func() {
+			config, err = LoadConfig(MtlsConfig...)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("prevents the client from hitting an authenticated endpoint", func() {
+			result, err := mtlsGenerate(client_certs.MissingAppGuid())
+
+			Expect(err).To(BeAssignableToTypeOf(&ApiError{}))
+			Expect(err.(*ApiError).StatusCode).To(Equal(401))
+			Expect(result).To(BeZero())
+		})
+	})
 })
 
 func TestMTLS(t *testing.T) {
@@ -133,62 +173,125 @@ func TestMTLS(t *testing.T) {
 	RunSpecs(t, "mTLS Test Suite")
 }
 
-func handleError(err error) {
-	if err != nil {
-		fmt.Println(err)
-		log.Fatal("Fatal", err)
-	}
-}
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+})
 
-func mtlsPost(url string, postData map[string]string, serverCaFilename, clientCertFilename, clientKeyPath string) (string, error) {
-	client, err := createMtlsClient(serverCaFilename, clientCertFilename, clientKeyPath)
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
 
-	jsonValue, _ := json.Marshal(postData)
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
 
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return "", err
-	}
+	config, err = LoadConfig(MtlsConfig...)
+	Expect(err).NotTo(HaveOccurred())
+	client, err := createMtlsClient(trustedFixture())
+	Expect(err).NotTo(HaveOccurred())
+	CleanupNode(client)
+}, func() {
+	StopSuite()
+})
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+func mtlsGenerate(fixture client_certs.Fixture) (Credential, error) {
+	client, err := createMtlsClient(fixture)
+	Expect(err).NotTo(HaveOccurred())
 
-	return string(body), nil
+	return client.Generate(GenerateUniqueCredentialName(), "password", nil, false)
 }
 
-func createMtlsClient(serverCaFilename, clientCertFilename, clientKeyFilename string) (*http.Client, error) {
-	serverCaPath := path.Join(config.CredentialRoot, serverCaFilename)
-	clientCertPath := path.Join(os.Getenv("PWD"), "certs", clientCertFilename)
-	clientKeyPath := path.Join(os.Getenv("PWD"), "certs", clientKeyFilename)
+// apiVerbs call every endpoint that accepts client certificates, for a JSON
+// credential created by a trusted client. Finds that succeed must return it.
+var apiVerbs = []struct {
+	description string
+	call        func(client *CredhubClient, existing Credential) error
+}{
+	{"get a credential by name", func(client *CredhubClient, existing Credential) error {
+		_, err := client.GetByName(existing.Name)
+		return err
+	}},
+	{"get a credential by id", func(client *CredhubClient, existing Credential) error {
+		_, err := client.GetById(existing.Id)
+		return err
+	}},
+	{"set a credential", func(client *CredhubClient, existing Credential) error {
+		_, err := client.Set(GenerateUniqueCredentialName(), "password", "some-password", true)
+		return err
+	}},
+	{"delete a credential", func(client *CredhubClient, existing Credential) error {
+		return client.Delete(existing.Name)
+	}},
+	{"find credentials by path", func(client *CredhubClient, existing Credential) error {
+		found, err := client.FindByPath(path.Dir(existing.Name))
+		if err == nil {
+			Expect(FoundNames(found)).To(ContainElement(existing.Name))
+		}
+		return err
+	}},
+	{"find credentials by name", func(client *CredhubClient, existing Credential) error {
+		found, err := client.FindByNameLike(path.Base(existing.Name))
+		if err == nil {
+			Expect(FoundNames(found)).To(ContainElement(existing.Name))
+		}
+		return err
+	}},
+	{"interpolate VCAP_SERVICES", func(client *CredhubClient, existing Credential) error {
+		_, err := client.Interpolate(map[string]interface{}{
+			"p-config-server": []interface{}{
+				map[string]interface{}{
+					"credentials": map[string]interface{}{"credhub-ref": "((" + existing.Name + "))"},
+					"label":       "p-config-server",
+				},
+			},
+		})
+		return err
+	}},
+}
 
-	_, err := os.Stat(serverCaPath)
-	handleError(err)
-	_, err = os.Stat(clientCertPath)
-	handleError(err)
-	_, err = os.Stat(clientKeyPath)
-	handleError(err)
+// forEveryVerb adds a spec per API verb that calls it with a client
+// authenticating with the given certificate and checks the error.
+func forEveryVerb(prefix string, fixture func() client_certs.Fixture, expectation func(err error)) {
+	for _, verb := range apiVerbs {
+		verb := verb
 
-	clientCertificate, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
-	handleError(err)
+		It(prefix+" "+verb.description, func() {
+			trustedClient, err := createMtlsClient(trustedFixture())
+			Expect(err).NotTo(HaveOccurred())
+			existing, err := trustedClient.Set("/"+GenerateUniqueCredentialName(), "json", map[string]string{"key": "value"}, true)
+			Expect(err).NotTo(HaveOccurred())
 
-	trustedCAs := x509.NewCertPool()
-	serverCA, err := ioutil.ReadFile(serverCaPath)
+			client, err := createMtlsClient(fixture())
+			Expect(err).NotTo(HaveOccurred())
 
-	ok := trustedCAs.AppendCertsFromPEM([]byte(serverCA))
-	if !ok {
-		log.Fatal("failed to parse root certificate")
+			expectation(verb.call(client, existing))
+		})
 	}
+}
 
-	tlsConf := &tls.Config{
-		Certificates: []tls.Certificate{clientCertificate},
-		RootCAs:      trustedCAs,
-	}
+func trustedFixture() client_certs.Fixture {
+	return client_certs.ForApp(appGuid)
+}
 
-	transport := &http.Transport{TLSClientConfig: tlsConf}
-	client := &http.Client{Transport: transport}
+func unknownCA() client_certs.Fixture {
+	fixture, err := client_certs.UnknownCA()
+	Expect(err).NotTo(HaveOccurred())
+	return fixture
+}
+
+func expectRejectedDuringHandshake(err error) {
+	Expect(err).To(HaveOccurred())
+	Expect(err.Error()).To(ContainSubstring("unknown certificate"))
+}
+
+func expectUnauthenticated(err error) {
+	Expect(err).To(BeAssignableToTypeOf(&ApiError{}))
+	Expect(err.Error()).To(MatchRegexp(".*Full authentication is required to access this resource"))
+}
 
-	return client, err
+// createMtlsClient issues a client certificate from the client CA in the
+// credential root and authenticates with it.
+func createMtlsClient(fixture client_certs.Fixture) (*CredhubClient, error) {
+	return NewFixtureClient(config, fixture)
 }
@@ -1,53 +1,64 @@
 package bbr_integration
 
 import (
-	"fmt"
-	"os"
-
-	. "github.com/onsi/ginkgo"
-	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gexec"
-
 	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
-	"os/exec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 
 	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 )
 
 func TestBbrIntegrationTest(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Backup and Restore integration suite")
+	RunSpecs(t, test_helpers.SuiteDescription("Backup and Restore integration suite"))
 }
 
-var config test_helpers.Config
-var tmpDir string
+var (
+	config         test_helpers.Config
+	client         *test_helpers.CredhubClient
+	backupRestorer test_helpers.BackupRestorer
+	tmpDir         string
+)
 
-var _ = BeforeSuite(func() {
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return test_helpers.StartSuite("")
+}, func(data []byte) {
+	test_helpers.JoinSuite(data)
 	SetDefaultEventuallyTimeout(15 * time.Minute)
 
 	var err error
 	config, err = test_helpers.LoadConfig()
 	Expect(err).NotTo(HaveOccurred())
 
-	tmpDir, err = ioutil.TempDir("", "BBR_CREDHUB_TEST")
+	// The local fake is backed up through its API, without a director.
+	required := test_helpers.BbrIntegrationConfig
+	if config.Local {
+		required = test_helpers.IntegrationConfig
+	}
+	config, err = test_helpers.LoadConfig(required...)
 	Expect(err).NotTo(HaveOccurred())
 
+	// Like the smoke suite, only verify CredHub's certificate when a
+	// credential root with server_ca_cert.pem is configured.
+	if config.CredentialRoot == "" {
+		client, err = test_helpers.NewTokenClientSkipTls(config)
+	} else {
+		client, err = test_helpers.NewTokenClient(config)
+	}
+	Expect(err).NotTo(HaveOccurred())
+	backupRestorer, err = test_helpers.NewBackupRestorer(config)
+	Expect(err).NotTo(HaveOccurred())
+
+	tmpDir, err = ioutil.TempDir("", "BBR_CREDHUB_TEST")
+	Expect(err).NotTo(HaveOccurred())
 })
 
-var _ = AfterSuite(func() {
+var _ = SynchronizedAfterSuite(func() {
 	Expect(os.RemoveAll(tmpDir)).To(Succeed())
+}, func() {
+	test_helpers.StopSuite()
 })
-
-func RunCommand(args ...string) *gexec.Session {
-	fmt.Printf("Running %s", args)
-	cmd := exec.Command(args[0], args[1:]...)
-
-	session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
-	Expect(err).NotTo(HaveOccurred())
-	<-session.Exited
-
-	return session
-}
@@ -2,82 +2,206 @@ package bbr_integration
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
 
 	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gbytes"
-	. "github.com/onsi/gomega/gexec"
 )
 
 var _ = Describe("Backup and Restore", func() {
 	var credentialName string
-	var bbrTestPath = "bbr_test"
+	var bbrTestPath = "/bbr_test"
+	var specPath string
+	var artifactsDir string
 
 	BeforeEach(func() {
-		credentialName = fmt.Sprintf("%s/%s", bbrTestPath, test_helpers.GenerateUniqueCredentialName())
+		// Each spec only writes, and cleans up, under its own path.
+		specPath = path.Join(bbrTestPath, test_helpers.GenerateUniqueCredentialName())
+		credentialName = path.Join(specPath, "password")
 
-		By("authenticating against credhub")
-		session := RunCommand("credhub", "api", "--server", config.ApiUrl)
-		Eventually(session).Should(Exit(0))
-
-		session = RunCommand("credhub", "login", "-u", config.ApiUsername, "-p", config.ApiPassword)
-		Eventually(session).Should(Exit(0))
-
-		CleanupCredhub(bbrTestPath)
+		var err error
+		artifactsDir, err = ioutil.TempDir(tmpDir, "artifacts")
+		Expect(err).NotTo(HaveOccurred())
 	})
 
 	AfterEach(func() {
-		CleanupCredhub(bbrTestPath)
-		CleanupArtifacts()
+		CleanupCredhub(specPath)
+		CleanupArtifacts(artifactsDir)
 	})
 
 	It("Successfully backs up and restores a Credhub release", func() {
 		By("adding a test credential")
-		session := RunCommand("credhub", "set", "--name", credentialName, "--type", "password", "-w", "originalsecret")
-		Eventually(session).Should(Exit(0))
+		_, err := client.Set(credentialName, "password", "originalsecret", true)
+		Expect(err).NotTo(HaveOccurred())
 
 		By("running bbr backup")
-		session = RunCommand("bbr", "director", "--private-key-path", config.Bosh.SshPrivateKeyPath,
-			"--username", config.Bosh.SshUsername, "--host", config.Bosh.Host, "backup")
-		Eventually(session).Should(Exit(0))
+		artifactPath, err := backupRestorer.Backup(artifactsDir)
+		Expect(err).NotTo(HaveOccurred())
 
 		By("asserting that the backup archive exists and contains a pg dump file")
-		session = RunCommand("sh", "-c", fmt.Sprintf("tar -xvf ./%s*Z/bosh*credhub.tar", config.DirectorHost))
-		Eventually(session).Should(Exit(0))
-		Eventually(RunCommand("ls", "credhubdb_dump")).Should(Exit(0))
+		Expect(artifactPath).To(BeADirectory())
+		dump, err := backupRestorer.DatabaseDump(artifactPath, artifactsDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dump).To(BeARegularFile())
 
 		By("editing the test credential")
-		session = RunCommand("credhub", "set", "--name", credentialName, "--type", "password", "-w", "updatedsecret")
-		Eventually(session).Should(Exit(0))
+		_, err = client.Set(credentialName, "password", "updatedsecret", true)
+		Expect(err).NotTo(HaveOccurred())
 
-		editSession := RunCommand("credhub", "get", "--name", credentialName)
-		Eventually(editSession).Should(Exit(0))
-		Eventually(editSession.Out).Should(gbytes.Say("value: updatedsecret"))
+		edited, err := client.GetByName(credentialName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(edited.AsPassword().Value).To(Equal("updatedsecret"))
 
 		By("running bbr restore")
-		session = RunCommand("sh", "-c",
-			fmt.Sprintf("bbr director --private-key-path %s --username %s --host %s restore --artifact-path ./%s*Z/",
-				config.Bosh.SshPrivateKeyPath, config.Bosh.SshUsername, config.Bosh.Host,config.DirectorHost))
-		Eventually(session).Should(Exit(0))
+		Expect(backupRestorer.Restore(artifactPath)).To(Succeed())
 
 		By("checking if the test credentials was restored")
-		getSession := RunCommand("credhub", "get", "--name", credentialName)
-		Eventually(getSession).Should(Exit(0))
-		Eventually(getSession.Out).Should(gbytes.Say("value: originalsecret"))
+		restored, err := client.GetByName(credentialName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.AsPassword().Value).To(Equal("originalsecret"))
+	})
+
+	It("restores every version of every type of credential unchanged", func() {
+		By("adding two versions of each type of credential")
+		names := seedEveryType(specPath)
+
+		backedUp, err := test_helpers.TakeSnapshot(client, names...)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("running bbr backup")
+		artifactPath, err := backupRestorer.Backup(artifactsDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("adding a version of each credential")
+		for _, name := range names {
+			oldest := backedUp.Versions[name][1]
+			_, err := client.Set(name, oldest.Type, setValue(oldest), true)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		By("running bbr restore")
+		Expect(backupRestorer.Restore(artifactPath)).To(Succeed())
+
+		By("checking that every version, id, timestamp and CA was restored")
+		restored, err := test_helpers.TakeSnapshot(client, names...)
+		Expect(err).NotTo(HaveOccurred())
+		diff := backedUp.Diff(restored)
+		Expect(diff).To(BeEmpty(), "restored credentials differ from the backup:\n%s", strings.Join(diff, "\n"))
+	})
+
+	It("does not write credential values to the backup unencrypted", func() {
+		if config.Local {
+			Skip("the fake's dump is not CredHub's database, so only a real bbr backup can show whether values are encrypted")
+		}
+
+		By("adding every type of credential and the sentinel value CI looks for")
+		names := seedEveryType(specPath)
+		sentinel := path.Join(specPath, "sentinel")
+		_, err := client.Set(sentinel, "value", test_helpers.FakeCredentialValue, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		seeded, err := test_helpers.TakeSnapshot(client, append(names, sentinel)...)
+		Expect(err).NotTo(HaveOccurred())
+		secrets, err := seeded.Secrets()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(ContainElement(test_helpers.FakeCredentialValue))
+
+		By("running bbr backup")
+		artifactPath, err := backupRestorer.Backup(artifactsDir)
+		Expect(err).NotTo(HaveOccurred())
+		dump, err := backupRestorer.DatabaseDump(artifactPath, artifactsDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("searching the database dump for the plaintext values")
+		leaked, err := test_helpers.FindPlaintext(dump, secrets)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leaked).To(BeEmpty(), "the database dump contains %d of %d secrets unencrypted", len(leaked), len(secrets))
 	})
 })
 
+// seedEveryType writes two versions of a credential of each type under
+// prefix, including a certificate chain whose intermediate and leaf are issued
+// by different versions of their CA, and returns their names.
+func seedEveryType(prefix string) []string {
+	name := func(leaf string) string { return path.Join(prefix, leaf) }
+	set := func(leaf, credentialType string, value interface{}) {
+		_, err := client.Set(name(leaf), credentialType, value, true)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	generate := func(leaf, credentialType string, parameters map[string]interface{}) test_helpers.Credential {
+		generated, err := client.Generate(name(leaf), credentialType, parameters, true)
+		Expect(err).NotTo(HaveOccurred())
+		return generated
+	}
+
+	for version := 1; version <= 2; version++ {
+		set("value", "value", fmt.Sprintf("value-secret-%d", version))
+		set("json", "json", map[string]interface{}{"version": version, "nested": map[string]string{"secret": fmt.Sprintf("json-secret-%d", version)}})
+		generate("password", "password", nil)
+		generate("user", "user", map[string]interface{}{"username": fmt.Sprintf("user-%d", version)})
+		generate("ssh", "ssh", nil)
+		generate("rsa", "rsa", nil)
+
+		generate("root-ca", "certificate", map[string]interface{}{"common_name": "root", "is_ca": true, "self_sign": true})
+		generate("intermediate-ca", "certificate", map[string]interface{}{"common_name": "intermediate", "is_ca": true, "ca": name("root-ca")})
+		leaf := generate("leaf", "certificate", map[string]interface{}{"common_name": "leaf", "ca": name("intermediate-ca")})
+		set("imported-leaf", "certificate", map[string]string{
+			"ca_name":     name("intermediate-ca"),
+			"certificate": leaf.AsCertificate().Value.Certificate,
+			"private_key": leaf.AsCertificate().Value.PrivateKey,
+		})
+	}
+
+	return []string{
+		name("value"), name("json"), name("password"), name("user"), name("ssh"), name("rsa"),
+		name("root-ca"), name("intermediate-ca"), name("leaf"), name("imported-leaf"),
+	}
+}
+
+// setValue is the value to set to write credential's value again. Gets also
+// return values CredHub derives, such as a user's password hash and an SSH
+// key's fingerprint, which sets reject.
+func setValue(credential test_helpers.Credential) interface{} {
+	switch credential.Type {
+	case "value":
+		return credential.AsValue().Value
+	case "password":
+		return credential.AsPassword().Value
+	case "json":
+		return credential.AsJSON().Value
+	case "user":
+		user := credential.AsUser().Value
+		return map[string]string{"username": user.Username, "password": user.Password}
+	case "ssh":
+		ssh := credential.AsSSH().Value
+		return map[string]string{"public_key": ssh.PublicKey, "private_key": ssh.PrivateKey}
+	case "rsa":
+		rsa := credential.AsRSA().Value
+		return map[string]string{"public_key": rsa.PublicKey, "private_key": rsa.PrivateKey}
+	case "certificate":
+		certificate := credential.AsCertificate().Value
+		value := map[string]string{}
+		for key, field := range map[string]string{"ca": certificate.Ca, "certificate": certificate.Certificate, "private_key": certificate.PrivateKey} {
+			if field != "" {
+				value[key] = field
+			}
+		}
+		return value
+	}
+	Fail("cannot set a " + credential.Type + " credential")
+	return nil
+}
+
 func CleanupCredhub(path string) {
-	By("Cleaning up credhub bbr test passwords")
-	RunCommand(
-		"sh", "-c",
-		fmt.Sprintf("credhub find -p /%s | tail -n +2 | cut -d\" \" -f1 | xargs -IN credhub delete --name N", path),
-	)
+	By("Cleaning up credhub bbr test credentials")
+	Expect(test_helpers.DeletePath(client, path)).To(BeEmpty())
 }
 
-func CleanupArtifacts() {
+func CleanupArtifacts(dir string) {
 	By("Cleaning up bbr test artifacts")
-	RunCommand("rm", "-rf", "credhubdb_dump")
-	RunCommand("sh", "-c", fmt.Sprintf("rm -rf %s*Z", config.DirectorHost))
+	Expect(os.RemoveAll(dir)).To(Succeed())
 }
@@ -0,0 +1,69 @@
+package benchmark_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/benchmark"
+	. "github.com/onsi/ginkgo"
+	ginkgoconfig "github.com/onsi/ginkgo/config"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	config   Config
+	clients  []*CredhubClient
+	baseline *benchmark.Baseline
+	results  []benchmark.Result
+)
+
+func TestBenchmark(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, SuiteDescription("Benchmark Suite"))
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	// Each node only has its own results, so recording from several would
+	// leave baseline.json with whichever node saved last.
+	Expect(benchmark.Record && ginkgoconfig.GinkgoConfig.ParallelTotal > 1).To(BeFalse(),
+		"-benchmark-record cannot be used when running in parallel")
+
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+
+	var err error
+	config, err = LoadConfig(IntegrationConfig...)
+	Expect(err).NotTo(HaveOccurred())
+
+	// Each worker has its own client, and so its own connections.
+	clients = nil
+	for i := 0; i < benchmark.Concurrency; i++ {
+		client, err := NewTokenClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		clients = append(clients, client)
+	}
+
+	baseline, err = benchmark.LoadBaseline(benchmark.BaselinePath)
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
+
+var _ = SynchronizedAfterSuite(func() {
+	benchmark.PrintResults(os.Stdout, results)
+
+	ReportCleanup()
+}, func() {
+	if benchmark.Record && len(results) > 0 {
+		baseline.Record(benchmark.Target(config), results)
+		Expect(baseline.Save(benchmark.BaselinePath)).To(Succeed())
+	}
+
+	CleanupRun(clients[0])
+
+	StopSuite()
+})
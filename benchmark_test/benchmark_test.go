@@ -0,0 +1,48 @@
+package benchmark_test
+
+import (
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/benchmark"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("benchmarks", func() {
+	var root string
+
+	BeforeEach(func() {
+		root = "/" + GenerateUniqueCredentialName()
+	})
+
+	AfterEach(func() {
+		Expect(DeletePath(clients[0], root)).To(BeEmpty())
+	})
+
+	for _, scenario := range benchmark.Scenarios {
+		scenario := scenario
+
+		It(scenario.Name+" does not regress past the baseline", func() {
+			operation, err := scenario.Prepare(clients[0], path.Join(root, scenario.Name))
+			Expect(err).NotTo(HaveOccurred())
+
+			result := benchmark.Run(scenario.Name, clients, benchmark.Duration, benchmark.MinOperations, operation)
+			results = append(results, result)
+			benchmark.PrintResults(GinkgoWriter, []benchmark.Result{result})
+
+			regressions, err := baseline.Compare(benchmark.Target(config), result)
+			if benchmark.Record || err != nil {
+				Expect(result.FirstError).NotTo(HaveOccurred(), "%d of %d operations failed", result.Errors, result.Operations)
+			}
+			if benchmark.Record {
+				return
+			}
+			if err != nil {
+				Fail(err.Error() + ", record one with -benchmark-record")
+			}
+
+			Expect(regressions).To(BeEmpty())
+		})
+	}
+})
@@ -0,0 +1,163 @@
+package ca_rotation_test
+
+import (
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rotating CAs", func() {
+	var (
+		base                                 string
+		rootName, intermediateName, leafName string
+		root, intermediate, leaf             CertificateCredential
+	)
+
+	BeforeEach(func() {
+		base = "/" + GenerateUniqueCredentialName()
+		rootName = path.Join(base, "root")
+		intermediateName = path.Join(base, "intermediate")
+		leafName = path.Join(base, "leaf")
+
+		root = generate(rootName, map[string]interface{}{"common_name": "rotation root", "is_ca": true})
+		intermediate = generate(intermediateName, map[string]interface{}{"common_name": "rotation intermediate", "is_ca": true, "ca": rootName})
+		leaf = generate(leafName, map[string]interface{}{"common_name": "rotation leaf", "ca": intermediateName})
+	})
+
+	Describe("regenerating the root", func() {
+		var newRoot CertificateCredential
+
+		BeforeEach(func() {
+			newRoot = regenerate(rootName)
+		})
+
+		It("creates a new self-signed version with a new key", func() {
+			Expect(newRoot.Id).NotTo(Equal(root.Id))
+			Expect(newRoot.Certificate().Equal(root.Certificate())).To(BeFalse())
+			Expect(newRoot.Certificate().SubjectKeyId).NotTo(Equal(root.Certificate().SubjectKeyId))
+			expectSignedBy(newRoot, newRoot)
+		})
+
+		It("leaves the intermediate and the leaf signed by the previous versions", func() {
+			expectSignedBy(get(intermediateName), root)
+			expectSignedBy(get(leafName), intermediate)
+		})
+
+		It("signs the intermediate with the new root once it is regenerated", func() {
+			newIntermediate := regenerate(intermediateName)
+			expectSignedBy(newIntermediate, newRoot)
+			expectSignedBy(get(intermediateName), newRoot)
+
+			By("leaving the leaf signed by the previous intermediate until it is regenerated")
+			expectSignedBy(get(leafName), intermediate)
+
+			newLeaf := regenerate(leafName)
+			expectSignedBy(newLeaf, newIntermediate)
+			expectSignedBy(get(leafName), newIntermediate)
+			ExpectChainVerifies([]CertificateCredential{newRoot, newIntermediate, newLeaf})
+		})
+
+		It("signs newly generated intermediates with the new root", func() {
+			otherIntermediateName := path.Join(base, "other-intermediate")
+			otherIntermediate := generate(otherIntermediateName, map[string]interface{}{"common_name": "rotation other intermediate", "is_ca": true, "ca": rootName})
+			expectSignedBy(otherIntermediate, newRoot)
+
+			otherLeaf := generate(path.Join(base, "other-leaf"), map[string]interface{}{"common_name": "rotation other leaf", "ca": otherIntermediateName})
+			expectSignedBy(otherLeaf, otherIntermediate)
+			ExpectChainVerifies([]CertificateCredential{newRoot, otherIntermediate, otherLeaf})
+		})
+	})
+
+	Describe("regenerating the intermediate", func() {
+		var newIntermediate CertificateCredential
+
+		BeforeEach(func() {
+			newIntermediate = regenerate(intermediateName)
+		})
+
+		It("creates a new version with a new key, still signed by the root", func() {
+			Expect(newIntermediate.Id).NotTo(Equal(intermediate.Id))
+			Expect(newIntermediate.Certificate().SubjectKeyId).NotTo(Equal(intermediate.Certificate().SubjectKeyId))
+			expectSignedBy(newIntermediate, root)
+			Expect(get(rootName).Id).To(Equal(root.Id))
+		})
+
+		It("leaves the existing leaf signed by the previous version until it is regenerated", func() {
+			expectSignedBy(get(leafName), intermediate)
+
+			newLeaf := regenerate(leafName)
+			expectSignedBy(newLeaf, newIntermediate)
+			expectSignedBy(get(leafName), newIntermediate)
+			ExpectChainVerifies([]CertificateCredential{root, newIntermediate, newLeaf})
+		})
+
+		It("signs newly generated leaves with the new version", func() {
+			newLeaf := generate(path.Join(base, "new-leaf"), map[string]interface{}{"common_name": "rotation new leaf", "ca": intermediateName})
+			expectSignedBy(newLeaf, newIntermediate)
+			ExpectChainVerifies([]CertificateCredential{root, newIntermediate, newLeaf})
+		})
+	})
+
+	Describe("after rotating both CAs", func() {
+		var newRoot, newIntermediate CertificateCredential
+
+		BeforeEach(func() {
+			newRoot = regenerate(rootName)
+			newIntermediate = regenerate(intermediateName)
+			regenerate(leafName)
+		})
+
+		It("keeps the previous versions retrievable by id", func() {
+			for _, previous := range []CertificateCredential{root, intermediate, leaf} {
+				retrieved, err := client.GetById(previous.Id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(retrieved.AsCertificate().Value).To(Equal(previous.Value), "version %s of %s", previous.Id, previous.Name)
+			}
+
+			By("still verifying the previous chain from the previous versions")
+			ExpectChainVerifies([]CertificateCredential{root, intermediate, leaf})
+		})
+
+		It("lists every version of each CA, newest first", func() {
+			for _, versions := range [][]CertificateCredential{{newRoot, root}, {newIntermediate, intermediate}} {
+				all, err := client.GetAllVersions(versions[0].Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(all).To(HaveLen(2))
+				Expect(all[0].Id).To(Equal(versions[0].Id))
+				Expect(all[1].Id).To(Equal(versions[1].Id))
+			}
+		})
+	})
+})
+
+func generate(name string, parameters map[string]interface{}) CertificateCredential {
+	generated, err := client.Generate(name, "certificate", parameters, true)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return generated.AsCertificate()
+}
+
+func regenerate(name string) CertificateCredential {
+	regenerated, err := client.Regenerate(name)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return regenerated.AsCertificate()
+}
+
+func get(name string) CertificateCredential {
+	current, err := client.GetByName(name)
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+	return current.AsCertificate()
+}
+
+// expectSignedBy checks that ca's version signed the certificate, and that the
+// certificate's ca field is that version.
+func expectSignedBy(certificate, ca CertificateCredential) {
+	cert, caCert := certificate.Certificate(), ca.Certificate()
+	ExpectWithOffset(1, cert.CheckSignatureFrom(caCert)).To(Succeed(), "%s is not signed by version %s of %s", certificate.Name, ca.Id, ca.Name)
+	// Self-signed certificates may leave out the authority key id.
+	if !cert.Equal(caCert) || len(cert.AuthorityKeyId) > 0 {
+		ExpectWithOffset(1, cert.AuthorityKeyId).To(Equal(caCert.SubjectKeyId), "authority key id of %s", certificate.Name)
+	}
+	ExpectWithOffset(1, certificate.Value.Ca).To(Equal(ca.Value.Certificate), "ca field of %s", certificate.Name)
+}
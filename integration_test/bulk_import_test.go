@@ -7,8 +7,6 @@ import (
 	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 )
 var (
-	credentialNamesSet []string
-	credentialNamesGet []string
 	session *Session
 )
 
@@ -65,8 +63,6 @@ var _ = Describe("Import test", func() {
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
-
-		afterSet()
 	})
 
 	It("should save the credentials on CredHub", func() {
@@ -132,10 +128,10 @@ var _ = Describe("Import test", func() {
 		Expect(stdOut).To(ContainSubstring(`username: dan-user2`))
 		Expect(stdOut).To(ContainSubstring(`password_hash:`))
 
-		session = RunCommand("get", "-n", "/director/deployment/json1")
+		session = RunCommand("get", "-n", "/director/deployment/json2")
 		Eventually(session).Should(Exit(0))
 		stdOut = string(session.Out.Contents())
-		Expect(stdOut).To(ContainSubstring(`name: /director/deployment/json1`))
+		Expect(stdOut).To(ContainSubstring(`name: /director/deployment/json2`))
 		Expect(stdOut).To(ContainSubstring(`type: json`))
 		Expect(stdOut).To(ContainSubstring(`value:`))
 		Expect(stdOut).To(ContainSubstring(`trump:`))
@@ -143,8 +139,6 @@ var _ = Describe("Import test", func() {
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
 		Expect(stdOut).To(ContainSubstring(`- covfefe`))
-
-		afterGet()
 	})
 
 })
@@ -152,43 +146,13 @@ var _ = Describe("Import test", func() {
 func beforeSet() {
 	session = RunCommand("generate", "-n", "ca-certificate1", "-t", "certificate", "-c", "credhub-ca", "-o", "pivotal", "-u", "credhub", "-i", "nyc", "-s", "NY", "-y", "US", "--is-ca", "--self-sign")
 	Eventually(session).Should(Exit(0))
-	credentialNamesSet = []string{"ca-certificate1"}
 	session = RunCommand("import", "-f", "../test_helpers/bulk_import_set.yml")
 	Eventually(session).Should(Exit(0))
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/blobstore-agent1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/blobstore-director1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/bosh-ca1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/bosh-cert1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/ssh-cred1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/rsa-cred1")
-	credentialNamesSet = append(credentialNamesSet, "/director/deployment/user1")
-}
-
-func afterSet() {
-	for _, credentialName := range credentialNamesSet {
-		session = RunCommand("delete", "-n", credentialName)
-		Eventually(session).Should(Exit(0))
-	}
 }
 
 func beforeGet() {
 	session = RunCommand("generate", "-n", "ca-certificate2", "-t", "certificate", "-c", "credhub-ca", "-o", "pivotal", "-u", "credhub", "-i", "nyc", "-s", "NY", "-y", "US", "--is-ca", "--self-sign")
 	Eventually(session).Should(Exit(0))
-	credentialNamesGet = []string{"ca-certificate2"}
 	session = RunCommand("import", "-f", "../test_helpers/bulk_import_get.yml")
 	Eventually(session).Should(Exit(0))
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/blobstore-agent2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/blobstore-director2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/bosh-ca2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/bosh-cert2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/ssh-cred2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/rsa-cred2")
-	credentialNamesGet = append(credentialNamesGet, "/director/deployment/user2")
-}
-
-func afterGet() {
-	for _, credentialName := range credentialNamesGet {
-		session = RunCommand("delete", "-n", credentialName)
-		Eventually(session).Should(Exit(0))
-	}
 }
@@ -0,0 +1,24 @@
+package integration_test
+
+import (
+	"fmt"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+)
+
+var _ = Describe("Certificate chains", func() {
+	for _, depth := range ChainDepths {
+		depth := depth
+
+		It(fmt.Sprintf("should verify a leaf signed through %d intermediate CAs up to the root", depth), func() {
+			chain := []CertificateCredential{}
+			for _, link := range ChainLinks(GenerateUniqueCredentialName(), depth) {
+				args := append([]string{"generate", "-n", link.Name, "-t", "certificate"}, link.Flags()...)
+				chain = append(chain, RunCredentialCommand(args...).AsCertificate())
+			}
+
+			ExpectChainVerifies(chain)
+		})
+	}
+})
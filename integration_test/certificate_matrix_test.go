@@ -0,0 +1,29 @@
+package integration_test
+
+import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+)
+
+var _ = Describe("Certificate generation matrix", func() {
+	for _, certificateCase := range CertificateCases {
+		certificateCase := certificateCase
+
+		It("should generate a certificate signed by a CA with "+certificateCase.Description, func() {
+			caName := GenerateNestedCredentialName("ca")
+			ca := RunCredentialCommand("generate", "-n", caName, "-t", "certificate", "--common-name", MatrixCaCommonName, "--is-ca").AsCertificate()
+
+			args := append([]string{"generate", "-n", GenerateUniqueCredentialName(), "-t", "certificate", "--ca", caName}, certificateCase.Flags()...)
+			credential := RunCredentialCommand(args...).AsCertificate()
+
+			ExpectCertificateMatches(credential, certificateCase, &ca)
+		})
+
+		It("should generate a self-signed certificate with "+certificateCase.Description, func() {
+			args := append([]string{"generate", "-n", GenerateUniqueCredentialName(), "-t", "certificate", "--self-sign"}, certificateCase.Flags()...)
+			credential := RunCredentialCommand(args...).AsCertificate()
+
+			ExpectCertificateMatches(credential, certificateCase, nil)
+		})
+	}
+})
@@ -0,0 +1,38 @@
+package integration_test
+
+import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("finding credentials by names containing LIKE metacharacters", func() {
+	var base string
+
+	BeforeEach(func() {
+		base = "/" + GenerateNestedCredentialName("like")
+		for _, name := range LikeMetacharacterNames {
+			session := RunCommand("set", "-n", base+"/"+name, "-t", "value", "-v", "metacharacters")
+			Eventually(session).Should(Exit(0))
+		}
+	})
+
+	for _, query := range NameLikeQueries {
+		query := query
+
+		It("finds by name only the literal matches for "+query.Description, func() {
+			found := RunFindCommand("-n", base+"/"+query.Query)
+			Expect(FoundNames(found)).To(ConsistOf(query.MatchesUnder(base)))
+		})
+	}
+
+	for _, query := range PathQueries {
+		query := query
+
+		It("finds by path only the literal matches for "+query.Description, func() {
+			found := RunFindCommand("-p", base+"/"+query.Query)
+			Expect(FoundNames(found)).To(ConsistOf(query.MatchesUnder(base)))
+		})
+	}
+})
@@ -18,11 +18,11 @@ var (
 )
 
 // We look for these values in the verify-logging CI task to ensure that credentials don't leak
-const credentialValue = "FAKE-CREDENTIAL-VALUE"
+const credentialValue = FakeCredentialValue
 
 func TestCommands(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Commands Suite")
+	RunSpecs(t, SuiteDescription("Commands Suite"))
 }
 
 var _ = BeforeEach(func() {
@@ -36,7 +36,7 @@ var _ = BeforeEach(func() {
 		os.Setenv("HOME", homeDir)
 	}
 
-	cfg, err = LoadConfig()
+	cfg, err = LoadConfig(IntegrationConfig...)
 	Expect(err).NotTo(HaveOccurred())
 
 	// These happen before each test due to the lack of a BeforeAll
@@ -46,6 +46,7 @@ var _ = BeforeEach(func() {
 })
 
 var _ = AfterEach(func() {
+	CleanupCredentials()
 	os.RemoveAll(homeDir)
 })
 
@@ -53,11 +54,20 @@ var _ = SynchronizedBeforeSuite(func() []byte {
 	path, err := Build("github.com/cloudfoundry-incubator/credhub-cli")
 	Expect(err).NotTo(HaveOccurred())
 
-	return []byte(path)
+	return StartSuite(path)
 }, func(data []byte) {
-	CommandPath = string(data)
+	JoinSuite(data)
 })
 
-var _ = SynchronizedAfterSuite(func() {}, func() {
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
+}, func() {
+	cfg, err := LoadConfig(IntegrationConfig...)
+	Expect(err).NotTo(HaveOccurred())
+	client, err := NewTokenClient(cfg)
+	Expect(err).NotTo(HaveOccurred())
+	CleanupRun(client)
+
+	StopSuite()
 	CleanupBuildArtifacts()
 })
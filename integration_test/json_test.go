@@ -1,35 +1,35 @@
 package integration_test
 
 import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gexec"
-	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 )
 
 var _ = Describe("json secrets", func() {
-	credentialName := GenerateUniqueCredentialName()
+	var credentialName string
 	credentialValue := `{"object":{"is":"complex"},"has":["an","array"]}`
-	credentialYaml := "value:\n  has:\n  - an\n  - array\n  object:\n    is: complex\n"
+	expectedValue := map[string]interface{}{
+		"object": map[string]interface{}{"is": "complex"},
+		"has":    []interface{}{"an", "array"},
+	}
+
+	BeforeEach(func() {
+		credentialName = GenerateUniqueCredentialName()
+	})
 
 	It("should set, get, and delete a new json secret", func() {
 		By("setting a new json secret", func() {
-			session := RunCommand("set", "-n", credentialName, "-t", "json", "-v", credentialValue)
-			Eventually(session).Should(Exit(0))
+			credential := RunCredentialCommand("set", "-n", credentialName, "-t", "json", "-v", credentialValue).AsJSON()
 
-			stdOut := string(session.Out.Contents())
-			Expect(stdOut).To(ContainSubstring(`type: json`))
-			Expect(stdOut).To(ContainSubstring(credentialYaml))
+			Expect(credential.Value).To(Equal(expectedValue))
 		})
 
 		By("getting the new json secret", func() {
-			session := RunCommand("get", "-n", credentialName)
-			stdOut := string(session.Out.Contents())
-
-			Eventually(session).Should(Exit(0))
+			credential := RunCredentialCommand("get", "-n", credentialName).AsJSON()
 
-			Expect(stdOut).To(ContainSubstring(`type: json`))
-			Expect(stdOut).To(ContainSubstring(credentialYaml))
+			Expect(credential.Value).To(Equal(expectedValue))
 		})
 
 		By("deleting the secret", func() {
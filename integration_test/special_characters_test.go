@@ -1,15 +1,15 @@
 package integration_test
 
 import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gexec"
-	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 )
 
 var _ = Describe("handling special characters", func() {
 	It("should handle secrets whose names begin with a leading slash", func() {
-		baseId := "ace/ventura" + GenerateUniqueCredentialName()
+		baseId := GenerateNestedCredentialName("ace", "ventura")
 		leadingSlashId := "/" + baseId
 		passwordValue := "finkel-is-einhorn"
 
@@ -19,23 +19,33 @@ var _ = Describe("handling special characters", func() {
 		})
 
 		By("retrieving the value that was set with a leading slash", func() {
-			session := RunCommand("get", "-n", leadingSlashId)
-			stdOut := string(session.Out.Contents())
-
-			Eventually(session).Should(Exit(0))
+			password := RunCredentialCommand("get", "-n", leadingSlashId).AsPassword()
 
-			Expect(stdOut).To(ContainSubstring(`type: password`))
-			Expect(stdOut).To(ContainSubstring(passwordValue))
+			Expect(password.Name).To(Equal(leadingSlashId))
+			Expect(password.Value).To(Equal(passwordValue))
 		})
 
 		By("retrieving the value that was set without a leading slash", func() {
-			session := RunCommand("get", "-n", baseId)
-			stdOut := string(session.Out.Contents())
+			password := RunCredentialCommand("get", "-n", baseId).AsPassword()
 
-			Eventually(session).Should(Exit(0))
+			Expect(password.Name).To(Equal(leadingSlashId))
+			Expect(password.Value).To(Equal(passwordValue))
+		})
+	})
+
+	It("should handle secrets whose names contain punctuation", func() {
+		name := GenerateSpecialCharacterCredentialName()
+
+		By("setting a value whose name contains punctuation", func() {
+			password := RunCredentialCommand("set", "-n", name, "-t", "password", "-w", "some-password").AsPassword()
+			Expect(password.Name).To(Equal("/" + name))
+		})
+
+		By("retrieving the value by the same name", func() {
+			password := RunCredentialCommand("get", "-n", name).AsPassword()
 
-			Expect(stdOut).To(ContainSubstring(`type: password`))
-			Expect(stdOut).To(ContainSubstring(passwordValue))
+			Expect(password.Name).To(Equal("/" + name))
+			Expect(password.Value).To(Equal("some-password"))
 		})
 	})
 })
@@ -4,90 +4,74 @@ import (
 	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	. "github.com/onsi/gomega/gexec"
 )
 
 var _ = Describe("Creating a User", func() {
+	var name string
+
+	BeforeEach(func() {
+		name = GenerateUniqueCredentialName()
+	})
+
 	Describe("User Generation", func() {
-		name := GenerateUniqueCredentialName()
 
 		Describe("With default parameters", func() {
 			It("should generate a user", func() {
-				session := RunCommand("generate", "-n", name, "-t", "user")
-				stdOut := string(session.Out.Contents())
+				var generated UserCredential
 
 				By("generating the credential first", func() {
-					Eventually(session).Should(Exit(0))
+					generated = RunCredentialCommand("generate", "-n", name, "-t", "user").AsUser()
 
-					Expect(stdOut).To(ContainSubstring(`name: /` + name))
-					Expect(stdOut).To(ContainSubstring(`type: user`))
-					Expect(stdOut).To(MatchRegexp(`username: \S*`))
-					Expect(stdOut).To(MatchRegexp(`password: \S*\d`))
-					Expect(stdOut).To(MatchRegexp(`password_hash: \$6\$.+\$.+`))
+					Expect(generated.Name).To(Equal("/" + name))
+					Expect(generated.Value.Username).NotTo(BeEmpty())
+					Expect(generated.Value.Password).To(MatchRegexp(`\d`))
+					Expect(generated.Value.PasswordHash).To(MatchRegexp(`^\$6\$.+\$.+`))
 				})
 
 				By("getting the generated credential", func() {
-					session := RunCommand("get", "-n", name)
-					stdOut := string(session.Out.Contents())
+					user := RunCredentialCommand("get", "-n", name).AsUser()
 
-					Eventually(session).Should(Exit(0))
-
-					Expect(stdOut).To(ContainSubstring(`name: /` + name))
-					Expect(stdOut).To(ContainSubstring(`type: user`))
-					Expect(stdOut).To(MatchRegexp(`username: \S*`))
-					Expect(stdOut).To(MatchRegexp(`password: \S*\d`))
-					Expect(stdOut).To(MatchRegexp(`password_hash: \$6\$.+\$.+`))
+					Expect(user.Name).To(Equal("/" + name))
+					Expect(user.Value).To(Equal(generated.Value))
 				})
 			})
 		})
 
 		Describe("with parameters", func() {
 			It("should generate a user with password of length 50", func() {
-				session := RunCommand("generate", "-n", name, "-t", "user", "--length", "50")
-				stdOut := string(session.Out.Contents())
-				Eventually(session).Should(Exit(0))
+				user := RunCredentialCommand("generate", "-n", name, "-t", "user", "--length", "50").AsUser()
 
-				Expect(stdOut).To(ContainSubstring(`name: /` + name))
-				Expect(stdOut).To(ContainSubstring(`type: user`))
-				Expect(stdOut).To(MatchRegexp(`username: \S*`))
-				Expect(stdOut).To(MatchRegexp(`password: \S{50}\b`))
-				Expect(stdOut).To(MatchRegexp(`password_hash: \$6\$.+\$.+`))
+				Expect(user.Name).To(Equal("/" + name))
+				Expect(user.Value.Username).NotTo(BeEmpty())
+				Expect(user.Value.Password).To(HaveLen(50))
+				Expect(user.Value.PasswordHash).To(MatchRegexp(`^\$6\$.+\$.+`))
 			})
 		})
 
 		Describe("with provided username", func() {
 			It("should generate a password, but not the username", func() {
 				username := "test-username"
-				session := RunCommand("generate", "-n", name, "-t", "user", "--username", username)
-				stdOut := string(session.Out.Contents())
-				Eventually(session).Should(Exit(0))
+				user := RunCredentialCommand("generate", "-n", name, "-t", "user", "--username", username).AsUser()
 
-				Expect(stdOut).To(ContainSubstring(`name: /` + name))
-				Expect(stdOut).To(ContainSubstring(`type: user`))
-				Expect(stdOut).To(ContainSubstring(`username: ` + username))
-				Expect(stdOut).To(MatchRegexp(`password: \S*\d`))
-				Expect(stdOut).To(MatchRegexp(`password_hash: \$6\$.+\$.+`))
+				Expect(user.Name).To(Equal("/" + name))
+				Expect(user.Value.Username).To(Equal(username))
+				Expect(user.Value.Password).To(MatchRegexp(`\d`))
+				Expect(user.Value.PasswordHash).To(MatchRegexp(`^\$6\$.+\$.+`))
 			})
 		})
 	})
 
 	Describe("Setting a User value", func() {
-		name := GenerateUniqueCredentialName()
-
 		Describe("including all parameters", func() {
 			It("should set the user value", func() {
 				username := "test"
 				password := "password"
-				session := RunCommand("set", "-n", name, "-t", "user", "-z", username, "-w", password)
-				stdOut := string(session.Out.Contents())
-				Eventually(session).Should(Exit(0))
-
-				Expect(stdOut).To(ContainSubstring(`name: /` + name))
-				Expect(stdOut).To(ContainSubstring(`type: user`))
-				Expect(stdOut).To(ContainSubstring(`username: ` + username))
-				Expect(stdOut).To(ContainSubstring(`password: ` + password))
-				Expect(stdOut).To(MatchRegexp(`password_hash: \$6\$.+\$.+`))
+				user := RunCredentialCommand("set", "-n", name, "-t", "user", "-z", username, "-w", password).AsUser()
 
+				Expect(user.Name).To(Equal("/" + name))
+				Expect(user.Value.Username).To(Equal(username))
+				Expect(user.Value.Password).To(Equal(password))
+				Expect(user.Value.PasswordHash).To(MatchRegexp(`^\$6\$.+\$.+`))
 			})
 		})
 	})
@@ -1,14 +1,13 @@
 package integration_test
 
 import (
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gexec"
-	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
-	"regexp"
 )
 
-var _ =	It("should set, get, and delete a new value secret", func() {
+var _ = It("should set, get, and delete a new value secret", func() {
 	credentialName := GenerateUniqueCredentialName()
 
 	By("trying to access a secret that doesn't exist", func() {
@@ -20,33 +19,19 @@ var _ =	It("should set, get, and delete a new value secret", func() {
 	})
 
 	By("setting a new value secret", func() {
-		session := RunCommand("set", "-n", credentialName, "-t", "value", "-v", credentialValue)
-		Eventually(session).Should(Exit(0))
+		credential := RunCredentialCommand("set", "-n", credentialName, "-t", "value", "-v", credentialValue).AsValue()
 
-		stdOut := string(session.Out.Contents())
-		Expect(stdOut).To(ContainSubstring(`type: value`))
-		Expect(stdOut).To(ContainSubstring("value: " + credentialValue))
+		Expect(credential.Value).To(Equal(credentialValue))
 	})
 
 	By("getting the new value secret", func() {
-		session := RunCommand("get", "-n", credentialName)
-		stdOut := string(session.Out.Contents())
+		credential := RunCredentialCommand("get", "-n", credentialName).AsValue()
 
-		Eventually(session).Should(Exit(0))
+		Expect(credential.Value).To(Equal(credentialValue))
 
-		Expect(stdOut).To(ContainSubstring(`type: value`))
-		Expect(stdOut).To(ContainSubstring("value: " + credentialValue))
-
-		re := regexp.MustCompile("id: (.*?)\n")
-		credentialId := re.FindStringSubmatch(stdOut)
-
-		session = RunCommand("get", "--id", credentialId[1])
-		stdOut = string(session.Out.Contents())
-
-		Eventually(session).Should(Exit(0))
-		Expect(stdOut).To(ContainSubstring(`type: value`))
-		Expect(stdOut).To(ContainSubstring("value: " + credentialValue))
+		byId := RunCredentialCommand("get", "--id", credential.Id).AsValue()
 
+		Expect(byId).To(Equal(credential))
 	})
 
 	By("deleting the secret", func() {
@@ -1,27 +1,27 @@
 package integration_test
 
 import (
-. "github.com/onsi/ginkgo"
-. "github.com/onsi/gomega"
-. "github.com/onsi/gomega/gexec"
-. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
-	"io/ioutil"
-	"net/http"
-	"strings"
-	"crypto/tls"
-)
+	"encoding/json"
 
-var (
-	config Config
-	err    error
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
 )
 
 var _ = Describe("vcap interpolation of secrets", func() {
-	credentialName := GenerateUniqueCredentialName()
 	credentialValue := `{"username":"bob", "password":"bob has a password"}`
 
+	var (
+		credentialName string
+		client         *CredhubClient
+	)
+
 	BeforeEach(func() {
-		config, err = LoadConfig()
+		credentialName = GenerateUniqueCredentialName()
+
+		var err error
+		client, err = NewTokenClient(cfg)
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -32,11 +32,7 @@ var _ = Describe("vcap interpolation of secrets", func() {
 		})
 
 		By("posting the VCAP_SERVICES JSON", func() {
-			session := RunCommand("--token")
-			Eventually(session).Should(Exit(0))
-			token := strings.TrimSpace(string(session.Out.Contents()))
-
-			postData := `{` +
+			vcapServices := `{` +
 				`"p-config-server": [` +
 				`   {` +
 				`     "credentials": {` +
@@ -47,34 +43,15 @@ var _ = Describe("vcap interpolation of secrets", func() {
 				` ]` +
 				`}`
 
-			result, statusCode, err := postJSON(config.ApiUrl + "/api/v1/interpolate", postData, token)
+			var services map[string]interface{}
+			Expect(json.Unmarshal([]byte(vcapServices), &services)).To(Succeed())
+
+			result, err := client.Interpolate(services)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(statusCode).To(Equal(200))
 
-			Expect(result).To(Equal(`{"p-config-server":[{"credentials":{"password":"bob has a password","username":"bob"},"label":"p-config-server"}]}`))
+			encoded, err := json.Marshal(result)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(encoded)).To(Equal(`{"p-config-server":[{"credentials":{"password":"bob has a password","username":"bob"},"label":"p-config-server"}]}`))
 		})
 	})
 })
-
-func postJSON(url string, postData string, token string) (string, int, error) {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(postData))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", token)
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", 0, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", 0, err
-	}
-
-	return string(body), resp.StatusCode, nil
-}
\ No newline at end of file
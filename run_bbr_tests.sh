@@ -2,19 +2,18 @@
 
 set -eu
 
-cat <<EOF > test_config.json
-{
-  "director_host":"${API_IP}",
-  "api_url": "https://${API_IP}:8844",
-  "api_username":"${USERNAME}",
-  "api_password":"${PASSWORD}",
-  "bosh": {
-    "host":"${API_IP}:22",
-    "bosh_ssh_username":"${BOSH_SSH_USERNAME}",
-    "bosh_ssh_private_key_path":"${BOSH_SSH_PRIVATE_KEY_PATH}"
-  },
-  "uaa_ca":"${UAA_CA}"
-}
-EOF
+export CREDHUB_DIRECTOR_HOST="${API_IP}"
+export CREDHUB_API_URL="https://${API_IP}:8844"
+export CREDHUB_API_USERNAME="${USERNAME}"
+export CREDHUB_API_PASSWORD="${PASSWORD}"
+export CREDHUB_BOSH_HOST="${API_IP}:22"
+export CREDHUB_BOSH_SSH_USERNAME="${BOSH_SSH_USERNAME}"
+export CREDHUB_BOSH_SSH_PRIVATE_KEY_PATH="${BOSH_SSH_PRIVATE_KEY_PATH}"
+export CREDHUB_UAA_CA="${UAA_CA}"
+# Optional: without it, the suite skips verifying CredHub's certificate.
+if [ -n "${CREDENTIAL_ROOT:-}" ]; then
+  export CREDHUB_CREDENTIAL_ROOT="${CREDENTIAL_ROOT}"
+fi
 
-ginkgo -r -p bbr_integration_test
+# Restores replace the whole database, so the specs cannot run in parallel.
+ginkgo -r bbr_integration_test
@@ -0,0 +1,12 @@
+#!/bin/bash
+
+set -eu
+
+export CREDHUB_API_URL=${API_URL:-https://localhost:9000}
+export CREDHUB_API_USERNAME=${USERNAME:-credhub}
+export CREDHUB_API_PASSWORD=${PASSWORD:-password}
+export CREDHUB_CREDENTIAL_ROOT=${CREDENTIAL_ROOT:-~/workspace/credhub-release/src/credhub/src/test/resources}
+export CREDHUB_UAA_CA=${UAA_CA:-~/workspace/credhub-deployments/ca/credhub_root_ca.pem}
+
+# Benchmarks in parallel would compete with each other, so run them one at a time.
+ginkgo benchmark_test -- "$@"
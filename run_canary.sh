@@ -0,0 +1,9 @@
+#!/bin/bash
+
+set -eu
+
+export CREDHUB_API_URL=${API_URL:-https://localhost:9000}
+export CREDHUB_API_USERNAME=${USERNAME:-credhub}
+export CREDHUB_API_PASSWORD=${PASSWORD:-password}
+
+go run ./test_helpers/canary/run_canary "$@"
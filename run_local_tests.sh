@@ -0,0 +1,8 @@
+#!/bin/bash
+
+set -eu
+
+export CREDHUB_LOCAL=true
+ginkgo -r -p -skipPackage bbr_integration_test,benchmark_test
+# Restores replace the whole store, so the BBR specs cannot run in parallel.
+ginkgo bbr_integration_test
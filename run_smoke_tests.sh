@@ -2,16 +2,8 @@
 
 set -eu
 
-API_URL=${API_URL:-https://localhost:9000}
-USERNAME=${USERNAME:-credhub}
-PASSWORD=${PASSWORD:-password}
-
-cat <<EOF > test_config.json
-{
-  "api_url": "${API_URL}",
-  "api_username":"${USERNAME}",
-  "api_password":"${PASSWORD}"
-}
-EOF
+export CREDHUB_API_URL=${API_URL:-https://localhost:9000}
+export CREDHUB_API_USERNAME=${USERNAME:-credhub}
+export CREDHUB_API_PASSWORD=${PASSWORD:-password}
 
 ginkgo -r -p smoke_test
@@ -0,0 +1,111 @@
+package smoke_test
+
+import (
+	"encoding/json"
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("Smoke Test across credential types", func() {
+	for _, credentialType := range []string{"password", "user", "ssh", "rsa"} {
+		credentialType := credentialType
+
+		It("can generate, get, regenerate and delete a "+credentialType, func() {
+			name := GenerateNestedCredentialName(credentialType)
+
+			generated := RunCredentialCommand("generate", "-n", name, "-t", credentialType)
+			Expect(generated.Type).To(Equal(credentialType))
+
+			got := RunCredentialCommand("get", "-n", name)
+			Expect(got.Id).To(Equal(generated.Id))
+			Expect(string(got.Value)).To(MatchJSON(string(generated.Value)))
+
+			regenerated := RunCredentialCommand("regenerate", "-n", name)
+			Expect(regenerated.Id).NotTo(Equal(generated.Id))
+			Expect(string(regenerated.Value)).NotTo(MatchJSON(string(generated.Value)))
+
+			expectDeleted(name)
+		})
+	}
+
+	It("can set, get, update and delete a value", func() {
+		name := GenerateNestedCredentialName("value")
+
+		RunCredentialCommand("set", "-n", name, "-t", "value", "-v", "smoke-value")
+		Expect(RunCredentialCommand("get", "-n", name).AsValue().Value).To(Equal("smoke-value"))
+
+		RunCredentialCommand("set", "-n", name, "-t", "value", "-v", "updated-smoke-value")
+		Expect(RunCredentialCommand("get", "-n", name).AsValue().Value).To(Equal("updated-smoke-value"))
+
+		expectDeleted(name)
+	})
+
+	It("can set, get, update and delete a json credential", func() {
+		name := GenerateNestedCredentialName("json")
+
+		RunCredentialCommand("set", "-n", name, "-t", "json", "-v", `{"smoke":"json"}`)
+		Expect(string(RunCredentialCommand("get", "-n", name).Value)).To(MatchJSON(`{"smoke":"json"}`))
+
+		RunCredentialCommand("set", "-n", name, "-t", "json", "-v", `{"smoke":["updated","json"]}`)
+		Expect(string(RunCredentialCommand("get", "-n", name).Value)).To(MatchJSON(`{"smoke":["updated","json"]}`))
+
+		expectDeleted(name)
+	})
+
+	It("can generate, get, regenerate and delete a CA-signed certificate", func() {
+		caName := GenerateNestedCredentialName("ca")
+		leafName := GenerateNestedCredentialName("leaf")
+
+		ca := RunCredentialCommand("generate", "-n", caName, "-t", "certificate", "-c", "smoke-ca", "--is-ca", "--self-sign").AsCertificate().Certificate()
+		generated := RunCredentialCommand("generate", "-n", leafName, "-t", "certificate", "-c", "smoke-leaf", "--ca", caName).AsCertificate()
+		Expect(generated.Certificate().CheckSignatureFrom(ca)).To(Succeed())
+		Expect(generated.Ca().Equal(ca)).To(BeTrue())
+
+		got := RunCredentialCommand("get", "-n", leafName).AsCertificate()
+		Expect(got.Value).To(Equal(generated.Value))
+
+		regenerated := RunCredentialCommand("regenerate", "-n", leafName).AsCertificate()
+		Expect(regenerated.Certificate().SerialNumber).NotTo(Equal(generated.Certificate().SerialNumber))
+		Expect(regenerated.Certificate().CheckSignatureFrom(ca)).To(Succeed())
+
+		expectDeleted(leafName)
+		expectDeleted(caName)
+	})
+
+	It("can find credentials by path and by name", func() {
+		first := GenerateNestedCredentialName("find")
+		second := path.Join(path.Dir(first), "second-"+path.Base(first))
+		RunCredentialCommand("set", "-n", first, "-t", "value", "-v", "found")
+		RunCredentialCommand("set", "-n", second, "-t", "value", "-v", "found")
+
+		Expect(FoundNames(RunFindCommand("-p", path.Dir(first)))).To(ConsistOf("/"+first, "/"+second))
+		Expect(FoundNames(RunFindCommand("-n", path.Base(first)))).To(ConsistOf("/"+first, "/"+second))
+	})
+
+	It("can interpolate a json credential into VCAP_SERVICES", func() {
+		name := GenerateNestedCredentialName("vcap")
+		RunCredentialCommand("set", "-n", name, "-t", "json", "-v", `{"username":"smoke","password":"smoke-password"}`)
+
+		client, err := NewTokenClientSkipTls(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		interpolated, err := client.Interpolate(map[string]interface{}{
+			"p-config-server": []interface{}{
+				map[string]interface{}{"credentials": map[string]string{"credhub-ref": "((/" + name + "))"}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		encoded, err := json.Marshal(interpolated)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(encoded)).To(MatchJSON(`{"p-config-server":[{"credentials":{"username":"smoke","password":"smoke-password"}}]}`))
+	})
+})
+
+func expectDeleted(name string) {
+	Eventually(RunCommand("delete", "-n", name)).Should(Exit(0))
+	Eventually(RunCommand("get", "-n", name)).Should(Exit(1))
+}
@@ -9,7 +9,12 @@ import (
 var _ = Describe("Smoke Test", func() {
 
 	Describe("certificates", func() {
-		certificate := "t_value" + GenerateUniqueCredentialName()
+		var certificate string
+
+		BeforeEach(func() {
+			certificate = GenerateNestedCredentialName("t_value")
+		})
+
 		It("can CRD certificates", func() {
 			By("should be able to set a certificate", func() {
 				session := RunCommand("set", "-n", certificate, "-t", "certificate", "--certificate", "iamacertificate")
@@ -28,23 +28,36 @@ var _ = BeforeEach(func() {
 		os.Setenv("HOME", homeDir)
 	}
 
-	cfg, err = LoadConfig()
+	cfg, err = LoadConfig(SmokeConfig...)
 	Expect(err).NotTo(HaveOccurred())
 
 	TargetAndLoginSkipTls(cfg)
 })
 
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
+
 func TestSmokeTest(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "SmokeTest Suite")
+	RunSpecs(t, SuiteDescription("SmokeTest Suite"))
 }
 
 var _ = SynchronizedBeforeSuite(func() []byte {
-	return []byte("credhub")
-}, func(cli_path []byte) {
-	CommandPath = string(cli_path)
+	return StartSuite("credhub")
+}, func(data []byte) {
+	JoinSuite(data)
 })
 
-var _ = SynchronizedAfterSuite(func() {}, func() {
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
+}, func() {
+	cfg, err := LoadConfig(SmokeConfig...)
+	Expect(err).NotTo(HaveOccurred())
+	client, err := NewTokenClientSkipTls(cfg)
+	Expect(err).NotTo(HaveOccurred())
+	CleanupRun(client)
+
+	StopSuite()
 	CleanupBuildArtifacts()
 })
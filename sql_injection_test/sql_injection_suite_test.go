@@ -0,0 +1,43 @@
+package sql_injection_test
+
+import (
+	"testing"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	config Config
+	client *CredhubClient
+)
+
+func TestSqlInjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, SuiteDescription("SQL Injection Suite"))
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+
+	var err error
+	config, err = LoadConfig(MtlsConfig...)
+	Expect(err).NotTo(HaveOccurred())
+	client, err = NewFixtureClient(config, client_certs.ForApp(client_certs.NewGuid()))
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
+
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
+	CleanupNode(client)
+}, func() {
+	StopSuite()
+})
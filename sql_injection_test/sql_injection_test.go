@@ -0,0 +1,58 @@
+package sql_injection_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/sql_injection"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQL injection", func() {
+	var (
+		fuzzer *sql_injection.Fuzzer
+		root   string
+	)
+
+	BeforeEach(func() {
+		// The canary is outside root, so no payload names it. It leaking, or
+		// disappearing, means a payload widened or rewrote a query.
+		canary, err := client.Set("/"+GenerateNestedCredentialName("canary"), "password", "canary-"+client_certs.NewGuid(), true)
+		Expect(err).NotTo(HaveOccurred())
+
+		root = "/" + GenerateNestedCredentialName("fuzz")
+		fuzzer = &sql_injection.Fuzzer{
+			Client:  client,
+			Root:    root,
+			Secrets: []string{canary.Name, canary.AsPassword().Value},
+			Invariant: func() error {
+				if _, err := client.GetByName(canary.Name); err != nil {
+					return fmt.Errorf("canary %s is gone: %s", canary.Name, err)
+				}
+				return nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(CleanupPath(client, root)).To(BeEmpty())
+	})
+
+	for _, target := range sql_injection.Targets {
+		target := target
+
+		It("finds no injection through "+target.Description, func() {
+			anomalies, err := fuzzer.Fuzz(target)
+			Expect(err).NotTo(HaveOccurred())
+
+			reasons := []string{}
+			for _, anomaly := range anomalies {
+				reasons = append(reasons, anomaly.String())
+			}
+			Expect(anomalies).To(BeEmpty(), strings.Join(reasons, "\n"))
+		})
+	}
+})
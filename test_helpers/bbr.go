@@ -0,0 +1,154 @@
+package test_helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// databaseDumpName is the file the CredHub backup script dumps the database to.
+const databaseDumpName = "credhubdb_dump"
+
+// BackupRestorer backs up and restores CredHub's database.
+type BackupRestorer interface {
+	// Backup writes a backup artifact under dir and returns its path.
+	Backup(dir string) (artifactPath string, err error)
+
+	// DatabaseDump returns the path of the database dump in an artifact,
+	// extracting it under dir if it is archived.
+	DatabaseDump(artifactPath, dir string) (string, error)
+
+	// Restore restores the database from an artifact returned by Backup.
+	Restore(artifactPath string) error
+}
+
+// NewBackupRestorer returns a BackupRestorer for the local fake server when
+// the config is local, and one that runs bbr against the director otherwise.
+func NewBackupRestorer(cfg Config) (BackupRestorer, error) {
+	if !cfg.Local {
+		return &Bbr{Bosh: *bosh(&cfg), DirectorHost: cfg.DirectorHost}, nil
+	}
+
+	client, err := NewTokenClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalBackupRestorer{Client: client}, nil
+}
+
+// Bbr runs `bbr director` for the CredHub deployed on a BOSH director.
+type Bbr struct {
+	Bosh         BoshConfig
+	DirectorHost string
+}
+
+func (b *Bbr) Backup(dir string) (string, error) {
+	if err := b.run(dir, "backup"); err != nil {
+		return "", err
+	}
+
+	// bbr names artifacts <host>_<timestamp>Z, so the newest sorts last.
+	artifacts, err := filepath.Glob(filepath.Join(dir, b.DirectorHost+"*Z"))
+	if err != nil {
+		return "", err
+	}
+	if len(artifacts) == 0 {
+		return "", fmt.Errorf("bbr backup did not write an artifact for %s to %s", b.DirectorHost, dir)
+	}
+	sort.Strings(artifacts)
+	return artifacts[len(artifacts)-1], nil
+}
+
+func (b *Bbr) DatabaseDump(artifactPath, dir string) (string, error) {
+	archives, err := filepath.Glob(filepath.Join(artifactPath, "bosh*credhub.tar"))
+	if err != nil {
+		return "", err
+	}
+	if len(archives) != 1 {
+		return "", fmt.Errorf("expected one CredHub archive in %s, found %v", artifactPath, archives)
+	}
+
+	extract := exec.Command("tar", "-xf", archives[0], "-C", dir)
+	extract.Stdout, extract.Stderr = GinkgoWriter, GinkgoWriter
+	if err := extract.Run(); err != nil {
+		return "", fmt.Errorf("extracting %s: %s", archives[0], err)
+	}
+
+	dump := filepath.Join(dir, databaseDumpName)
+	_, err = os.Stat(dump)
+	return dump, err
+}
+
+func (b *Bbr) Restore(artifactPath string) error {
+	return b.run(filepath.Dir(artifactPath), "restore", "--artifact-path", artifactPath)
+}
+
+func (b *Bbr) run(dir, command string, flags ...string) error {
+	args := append([]string{"director",
+		"--private-key-path", b.Bosh.SshPrivateKeyPath,
+		"--username", b.Bosh.SshUsername,
+		"--host", b.Bosh.Host,
+		command,
+	}, flags...)
+	fmt.Fprintf(GinkgoWriter, "Running bbr %s\n", strings.Join(args, " "))
+
+	cmd := exec.Command("bbr", args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = GinkgoWriter, GinkgoWriter
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("bbr %s: %s", command, err)
+	}
+	return nil
+}
+
+// LocalBackupRestorer snapshots the fake server's store into an artifact
+// directory laid out like an extracted bbr artifact.
+type LocalBackupRestorer struct {
+	Client *CredhubClient
+}
+
+func (l *LocalBackupRestorer) Backup(dir string) (string, error) {
+	status, dump, err := l.Client.Send("GET", "/fake/dump", nil)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("dumping the fake server failed with %d: %s", status, dump)
+	}
+
+	artifactPath := filepath.Join(dir, "local_"+time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.MkdirAll(artifactPath, 0700); err != nil {
+		return "", err
+	}
+	return artifactPath, ioutil.WriteFile(filepath.Join(artifactPath, databaseDumpName), dump, 0600)
+}
+
+func (l *LocalBackupRestorer) DatabaseDump(artifactPath, dir string) (string, error) {
+	dump := filepath.Join(artifactPath, databaseDumpName)
+	_, err := os.Stat(dump)
+	return dump, err
+}
+
+func (l *LocalBackupRestorer) Restore(artifactPath string) error {
+	dump, err := ioutil.ReadFile(filepath.Join(artifactPath, databaseDumpName))
+	if err != nil {
+		return err
+	}
+
+	status, body, err := l.Client.Send("PUT", "/fake/restore", dump)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		return fmt.Errorf("restoring the fake server failed with %d: %s", status, body)
+	}
+	return nil
+}
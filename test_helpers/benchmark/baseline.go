@@ -0,0 +1,183 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+)
+
+// Baseline holds the results to compare benchmarks with, for each target.
+type Baseline struct {
+	// Tolerance is how much worse than the baseline a result may be before
+	// it counts as a regression, e.g. 0.25 for 25%.
+	Tolerance float64                    `json:"tolerance"`
+	Targets   map[string]*TargetBaseline `json:"targets"`
+}
+
+// TargetBaseline holds the results for one target, recorded at one
+// concurrency.
+type TargetBaseline struct {
+	Concurrency int `json:"concurrency"`
+
+	// Tolerance, when set, replaces the baseline's tolerance for this target.
+	Tolerance float64 `json:"tolerance,omitempty"`
+
+	// ErrorsOnly leaves out the throughput and latency checks, for targets
+	// whose performance depends on the machine running the suite, like the
+	// local fake. Their results are still recorded, for reference.
+	ErrorsOnly bool `json:"errors_only,omitempty"`
+
+	Scenarios map[string]Thresholds `json:"scenarios"`
+}
+
+// Thresholds are the results a scenario is compared with.
+type Thresholds struct {
+	Throughput   float64 `json:"throughput"`
+	P50          float64 `json:"p50_ms"`
+	P95          float64 `json:"p95_ms"`
+	P99          float64 `json:"p99_ms"`
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+// latencySlack is how much slower than the baseline a latency may always be,
+// whatever the tolerance, since scheduling alone varies sub-millisecond
+// latencies by more than any sensible tolerance.
+const latencySlack = 1.0 // ms
+
+// Target names the baseline for cfg: its profile, "local" for the fake server,
+// or "default".
+func Target(cfg test_helpers.Config) string {
+	switch {
+	case cfg.Profile != "":
+		return cfg.Profile
+	case cfg.Local:
+		return "local"
+	default:
+		return "default"
+	}
+}
+
+// LoadBaseline reads a baseline file, returning an empty baseline if it does
+// not exist.
+func LoadBaseline(file string) (*Baseline, error) {
+	baseline := &Baseline{Tolerance: 0.25, Targets: map[string]*TargetBaseline{}}
+
+	contents, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return baseline, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, baseline); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+	if baseline.Targets == nil {
+		baseline.Targets = map[string]*TargetBaseline{}
+	}
+	return baseline, nil
+}
+
+// Save writes the baseline to file.
+func (b *Baseline) Save(file string) error {
+	contents, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(contents, '\n'), 0644)
+}
+
+// Compare describes each way result is worse than the baseline for target by
+// more than the tolerance, or for latencies by more than the tolerance and
+// latencySlack. Only the error rate is compared for ErrorsOnly targets. It
+// returns an error if there is no baseline to compare result with.
+func (b *Baseline) Compare(target string, result Result) ([]string, error) {
+	targetBaseline, ok := b.Targets[target]
+	if !ok {
+		return nil, fmt.Errorf("no baseline for target %q", target)
+	}
+	if targetBaseline.Concurrency != result.Concurrency {
+		return nil, fmt.Errorf("the baseline for target %q was recorded at concurrency %d, not %d",
+			target, targetBaseline.Concurrency, result.Concurrency)
+	}
+	thresholds, ok := targetBaseline.Scenarios[result.Scenario]
+	if !ok {
+		return nil, fmt.Errorf("no baseline for %s on target %q", result.Scenario, target)
+	}
+
+	tolerance := b.Tolerance
+	if targetBaseline.Tolerance != 0 {
+		tolerance = targetBaseline.Tolerance
+	}
+
+	regressions := []string{}
+	if result.ErrorRate() > thresholds.MaxErrorRate {
+		regressions = append(regressions, fmt.Sprintf("%d of %d operations failed, the first with: %v",
+			result.Errors, result.Operations, result.FirstError))
+	}
+	if targetBaseline.ErrorsOnly {
+		return regressions, nil
+	}
+
+	if minimum := thresholds.Throughput * (1 - tolerance); result.Throughput < minimum {
+		regressions = append(regressions, fmt.Sprintf("throughput %.1f/s is below %.1f/s (baseline %.1f/s)",
+			result.Throughput, minimum, thresholds.Throughput))
+	}
+	for _, latency := range []struct {
+		name     string
+		baseline float64
+		measured time.Duration
+	}{
+		{"p50", thresholds.P50, result.P50},
+		{"p95", thresholds.P95, result.P95},
+		{"p99", thresholds.P99, result.P99},
+	} {
+		measured := milliseconds(latency.measured)
+		if maximum := math.Max(latency.baseline*(1+tolerance), latency.baseline+latencySlack); measured > maximum {
+			regressions = append(regressions, fmt.Sprintf("%s latency %.1fms is above %.1fms (baseline %.1fms)",
+				latency.name, measured, maximum, latency.baseline))
+		}
+	}
+
+	return regressions, nil
+}
+
+// Record stores results without errors as the baseline for target. Scenarios
+// that did not run keep their baseline, unless it was recorded at another
+// concurrency.
+func (b *Baseline) Record(target string, results []Result) {
+	for _, result := range results {
+		if result.Errors > 0 {
+			continue
+		}
+		targetBaseline, ok := b.Targets[target]
+		if !ok || targetBaseline.Concurrency != result.Concurrency {
+			targetBaseline = &TargetBaseline{Concurrency: result.Concurrency, Scenarios: map[string]Thresholds{}}
+			if ok {
+				targetBaseline.Tolerance = b.Targets[target].Tolerance
+				targetBaseline.ErrorsOnly = b.Targets[target].ErrorsOnly
+			}
+			b.Targets[target] = targetBaseline
+		}
+		targetBaseline.Scenarios[result.Scenario] = Thresholds{
+			Throughput: roundTo(result.Throughput, 1),
+			P50:        roundTo(milliseconds(result.P50), 1),
+			P95:        roundTo(milliseconds(result.P95), 1),
+			P99:        roundTo(milliseconds(result.P99), 1),
+		}
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func roundTo(f float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(f*scale) / scale
+}
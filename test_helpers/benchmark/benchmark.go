@@ -0,0 +1,147 @@
+// Package benchmark drives CredHub API operations concurrently, measures their
+// throughput and latency, and compares the results with a checked-in baseline.
+package benchmark
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+)
+
+var (
+	Concurrency   int
+	Duration      time.Duration
+	MinOperations int
+	BaselinePath  string
+	Record        bool
+)
+
+func init() {
+	flag.IntVar(&Concurrency, "benchmark-concurrency", 10, "clients sending requests at once in each benchmark")
+	flag.DurationVar(&Duration, "benchmark-duration", 10*time.Second, "how long to run each benchmark for")
+	flag.IntVar(&MinOperations, "benchmark-min-operations", 100, "operations to send in each benchmark however long they take, so that slow ones have enough samples for the p99")
+	flag.StringVar(&BaselinePath, "benchmark-baseline", "baseline.json", "baseline file to compare benchmark results with")
+	flag.BoolVar(&Record, "benchmark-record", false, "write benchmark results to the baseline file instead of comparing with it")
+}
+
+// Operation sends one request. worker identifies the client sending it, from
+// 0 to the concurrency, and iteration counts that worker's requests.
+type Operation func(client *test_helpers.CredhubClient, worker, iteration int) error
+
+// Result summarizes one benchmark run.
+type Result struct {
+	Scenario    string
+	Concurrency int
+	Elapsed     time.Duration
+	Operations  int
+	Errors      int
+	FirstError  error
+	Throughput  float64
+	P50         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+}
+
+// ErrorRate is the fraction of operations that failed.
+func (r Result) ErrorRate() float64 {
+	if r.Operations == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Operations)
+}
+
+// Run sends operation from every client at once, each waiting for its last
+// request before sending the next, until duration has passed and at least
+// minOperations have been sent.
+func Run(scenario string, clients []*test_helpers.CredhubClient, duration time.Duration, minOperations int, operation Operation) Result {
+	var (
+		lock       sync.Mutex
+		wg         sync.WaitGroup
+		latencies  []time.Duration
+		errorCount int
+		firstError error
+		started    int64
+	)
+
+	start := time.Now()
+	deadline := start.Add(duration)
+	for worker, client := range clients {
+		wg.Add(1)
+		go func(worker int, client *test_helpers.CredhubClient) {
+			defer wg.Done()
+
+			workerLatencies := []time.Duration{}
+			for iteration := 0; ; iteration++ {
+				if !time.Now().Before(deadline) && atomic.LoadInt64(&started) >= int64(minOperations) {
+					break
+				}
+				atomic.AddInt64(&started, 1)
+
+				sent := time.Now()
+				err := operation(client, worker, iteration)
+				workerLatencies = append(workerLatencies, time.Since(sent))
+
+				if err != nil {
+					lock.Lock()
+					errorCount++
+					if firstError == nil {
+						firstError = err
+					}
+					lock.Unlock()
+				}
+			}
+
+			lock.Lock()
+			latencies = append(latencies, workerLatencies...)
+			lock.Unlock()
+		}(worker, client)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return Result{
+		Scenario:    scenario,
+		Concurrency: len(clients),
+		Elapsed:     elapsed,
+		Operations:  len(latencies),
+		Errors:      errorCount,
+		FirstError:  firstError,
+		Throughput:  float64(len(latencies)) / elapsed.Seconds(),
+		P50:         percentile(latencies, 50),
+		P95:         percentile(latencies, 95),
+		P99:         percentile(latencies, 99),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// PrintResults writes results as a table.
+func PrintResults(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-24s %11s %10s %10s %10s %10s %8s\n", "SCENARIO", "CONCURRENCY", "OPS/S", "P50", "P95", "P99", "ERRORS")
+	for _, result := range results {
+		fmt.Fprintf(w, "%-24s %11d %10.1f %10s %10s %10s %8d\n",
+			result.Scenario, result.Concurrency, result.Throughput,
+			round(result.P50), round(result.P95), round(result.P99), result.Errors)
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(100 * time.Microsecond)
+}
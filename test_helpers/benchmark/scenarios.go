@@ -0,0 +1,114 @@
+package benchmark
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+)
+
+// FindCredentials is how many credentials the find scenario searches through.
+const FindCredentials = 50
+
+// Scenario is an operation to benchmark. Prepare writes any credentials the
+// operation needs under root and returns the operation.
+type Scenario struct {
+	Name    string
+	Prepare func(client *test_helpers.CredhubClient, root string) (Operation, error)
+}
+
+// Scenarios writing credentials give each worker its own name and overwrite
+// it, so that they add versions rather than an unbounded number of names.
+var Scenarios = []Scenario{
+	{
+		Name: "set",
+		Prepare: func(_ *test_helpers.CredhubClient, root string) (Operation, error) {
+			return func(client *test_helpers.CredhubClient, worker, iteration int) error {
+				_, err := client.Set(workerName(root, worker), "password", fmt.Sprintf("value-%d", iteration), true)
+				return err
+			}, nil
+		},
+	},
+	{
+		Name: "get-by-name",
+		Prepare: func(client *test_helpers.CredhubClient, root string) (Operation, error) {
+			name := path.Join(root, "credential")
+			if _, err := client.Set(name, "password", "value", true); err != nil {
+				return nil, err
+			}
+			return func(client *test_helpers.CredhubClient, _, _ int) error {
+				_, err := client.GetByName(name)
+				return err
+			}, nil
+		},
+	},
+	{
+		Name: "get-by-id",
+		Prepare: func(client *test_helpers.CredhubClient, root string) (Operation, error) {
+			credential, err := client.Set(path.Join(root, "credential"), "password", "value", true)
+			if err != nil {
+				return nil, err
+			}
+			return func(client *test_helpers.CredhubClient, _, _ int) error {
+				_, err := client.GetById(credential.Id)
+				return err
+			}, nil
+		},
+	},
+	generateScenario("generate-password", "password", nil),
+	generateScenario("generate-rsa-4096", "rsa", map[string]interface{}{"key_length": 4096}),
+	{
+		Name: "generate-certificate",
+		Prepare: func(client *test_helpers.CredhubClient, root string) (Operation, error) {
+			caName := path.Join(root, "ca")
+			_, err := client.Generate(caName, "certificate", map[string]interface{}{
+				"common_name": "benchmark-ca",
+				"is_ca":       true,
+			}, true)
+			if err != nil {
+				return nil, err
+			}
+			return func(client *test_helpers.CredhubClient, worker, _ int) error {
+				_, err := client.Generate(workerName(root, worker), "certificate", map[string]interface{}{
+					"common_name": "benchmark",
+					"ca":          caName,
+				}, true)
+				return err
+			}, nil
+		},
+	},
+	{
+		Name: "find",
+		Prepare: func(client *test_helpers.CredhubClient, root string) (Operation, error) {
+			for i := 0; i < FindCredentials; i++ {
+				if _, err := client.Set(path.Join(root, strconv.Itoa(i)), "value", "value", true); err != nil {
+					return nil, err
+				}
+			}
+			return func(client *test_helpers.CredhubClient, _, _ int) error {
+				found, err := client.FindByPath(root)
+				if err == nil && len(found) != FindCredentials {
+					err = fmt.Errorf("found %d credentials under %s, expected %d", len(found), root, FindCredentials)
+				}
+				return err
+			}, nil
+		},
+	},
+}
+
+func generateScenario(name, credentialType string, parameters map[string]interface{}) Scenario {
+	return Scenario{
+		Name: name,
+		Prepare: func(_ *test_helpers.CredhubClient, root string) (Operation, error) {
+			return func(client *test_helpers.CredhubClient, worker, _ int) error {
+				_, err := client.Generate(workerName(root, worker), credentialType, parameters, true)
+				return err
+			}, nil
+		},
+	}
+}
+
+func workerName(root string, worker int) string {
+	return path.Join(root, "worker-"+strconv.Itoa(worker))
+}
@@ -0,0 +1,372 @@
+// Package canary runs the smoke test's create, read, update and delete cycle
+// against CredHub in a loop, and reports the latency and outcome of every
+// operation for monitoring.
+package canary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+)
+
+// Operations are the steps of a cycle, in order.
+var Operations = []string{"login", "set", "get", "generate", "regenerate", "find", "interpolate", "delete"}
+
+// LatencyBuckets are the upper bounds, in seconds, of the latency histogram.
+var LatencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Result is the outcome of one operation.
+type Result struct {
+	Operation string        `json:"operation"`
+	Time      time.Time     `json:"time"`
+	Latency   time.Duration `json:"latency_ns"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+}
+
+type operationStats struct {
+	successes uint64
+	failures  uint64
+	buckets   []uint64
+	sum       float64
+	last      Result
+}
+
+// Canary runs cycles and keeps the statistics to report.
+type Canary struct {
+	// Login returns a client to run cycles with. It is called before the
+	// first cycle and again after CredHub rejects the client's token.
+	Login func() (*test_helpers.CredhubClient, error)
+
+	// Path is where the canary's credentials are written.
+	Path string
+
+	// Window is how many of the most recent results WriteJSON writes.
+	Window int
+
+	client *test_helpers.CredhubClient
+
+	lock   sync.Mutex
+	stats  map[string]*operationStats
+	recent []Result
+	cycles uint64
+	up     bool
+}
+
+func New(login func() (*test_helpers.CredhubClient, error), credentialPath string) *Canary {
+	return &Canary{
+		Login:  login,
+		Path:   credentialPath,
+		Window: 100,
+		stats:  map[string]*operationStats{},
+	}
+}
+
+// Run runs a cycle every interval, writing jsonPath after each one when it is
+// set, until stop is closed.
+func (c *Canary) Run(interval time.Duration, jsonPath string, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.Cycle()
+		if jsonPath != "" {
+			if err := c.WriteJSON(jsonPath); err != nil {
+				fmt.Fprintf(os.Stderr, "writing %s: %s\n", jsonPath, err)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cycle writes, reads, regenerates, finds, interpolates and deletes the
+// canary's credentials once, and returns the result of every operation. When
+// CredHub rejects the client's token, the canary logs in again and retries the
+// operation once, so that token expiry does not fail the cycle.
+func (c *Canary) Cycle() []Result {
+	results := []Result{}
+	ok := true
+	finish := func(result Result, err error) {
+		result.Latency = time.Since(result.Time)
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			ok = false
+		}
+		results = append(results, result)
+	}
+	login := func() bool {
+		result := Result{Operation: "login", Time: time.Now().UTC()}
+		client, err := c.Login()
+		if err != nil {
+			client = nil
+		}
+		c.client = client
+		finish(result, err)
+		return client != nil
+	}
+	// step runs do with the logged in client. Once the canary has no client
+	// CredHub accepts, the rest of the cycle is skipped.
+	step := func(operation string, do func(client *test_helpers.CredhubClient) error) {
+		if c.client == nil {
+			return
+		}
+		result := Result{Operation: operation, Time: time.Now().UTC()}
+		err := do(c.client)
+		if isUnauthorized(err) && login() {
+			result.Time = time.Now().UTC()
+			err = do(c.client)
+		}
+		if isUnauthorized(err) {
+			c.client = nil
+		}
+		finish(result, err)
+	}
+
+	if c.client == nil && !login() {
+		c.record(results, false)
+		return results
+	}
+
+	jsonName := path.Join(c.Path, "json")
+	passwordName := path.Join(c.Path, "password")
+	value := map[string]string{"checked-at": time.Now().UTC().Format(time.RFC3339Nano)}
+
+	step("set", func(client *test_helpers.CredhubClient) error {
+		_, err := client.Set(jsonName, "json", value, true)
+		return err
+	})
+	step("get", func(client *test_helpers.CredhubClient) error {
+		credential, err := client.GetByName(jsonName)
+		if err != nil {
+			return err
+		}
+		// Decode the value here rather than with AsJSON, which asserts with
+		// Gomega and so only works inside a Ginkgo suite.
+		if credential.Type != "json" {
+			return fmt.Errorf("%s has type %s, expected json", jsonName, credential.Type)
+		}
+		decoded := map[string]interface{}{}
+		if err := json.Unmarshal(credential.Value, &decoded); err != nil {
+			return err
+		}
+		if got := decoded["checked-at"]; got != value["checked-at"] {
+			return fmt.Errorf("got %v, expected %s", got, value["checked-at"])
+		}
+		return nil
+	})
+	var generated test_helpers.Credential
+	step("generate", func(client *test_helpers.CredhubClient) (err error) {
+		generated, err = client.Generate(passwordName, "password", nil, true)
+		return err
+	})
+	step("regenerate", func(client *test_helpers.CredhubClient) error {
+		regenerated, err := client.Regenerate(passwordName)
+		if err == nil && regenerated.Id == generated.Id {
+			err = fmt.Errorf("regenerating %s returned the existing version", passwordName)
+		}
+		return err
+	})
+	step("find", func(client *test_helpers.CredhubClient) error {
+		found, err := client.FindByPath(c.Path)
+		if err != nil {
+			return err
+		}
+		names := test_helpers.FoundNames(found)
+		sort.Strings(names)
+		if len(names) != 2 || names[0] != jsonName || names[1] != passwordName {
+			return fmt.Errorf("found %v under %s", names, c.Path)
+		}
+		return nil
+	})
+	step("interpolate", func(client *test_helpers.CredhubClient) error {
+		interpolated, err := client.Interpolate(map[string]interface{}{
+			"canary": []interface{}{
+				map[string]interface{}{"credentials": map[string]string{"credhub-ref": "((" + jsonName + "))"}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		encoded, _ := json.Marshal(interpolated)
+		if !strings.Contains(string(encoded), value["checked-at"]) {
+			return fmt.Errorf("interpolated %s", encoded)
+		}
+		return nil
+	})
+	for _, name := range []string{jsonName, passwordName} {
+		name := name
+		step("delete", func(client *test_helpers.CredhubClient) error { return client.Delete(name) })
+	}
+
+	c.record(results, ok)
+	return results
+}
+
+func isUnauthorized(err error) bool {
+	apiError, ok := err.(*test_helpers.ApiError)
+	return ok && apiError.StatusCode == http.StatusUnauthorized
+}
+
+func (c *Canary) record(results []Result, up bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cycles++
+	c.up = up
+	for _, result := range results {
+		stats, ok := c.stats[result.Operation]
+		if !ok {
+			stats = &operationStats{buckets: make([]uint64, len(LatencyBuckets))}
+			c.stats[result.Operation] = stats
+		}
+		if result.Success {
+			stats.successes++
+		} else {
+			stats.failures++
+		}
+		seconds := result.Latency.Seconds()
+		stats.sum += seconds
+		for i, bound := range LatencyBuckets {
+			if seconds <= bound {
+				stats.buckets[i]++
+			}
+		}
+		stats.last = result
+	}
+
+	c.recent = append(c.recent, results...)
+	if len(c.recent) > c.Window {
+		c.recent = c.recent[len(c.recent)-c.Window:]
+	}
+}
+
+// ServeHTTP writes the statistics in the Prometheus text format.
+func (c *Canary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, c.Metrics())
+}
+
+// Metrics returns the statistics in the Prometheus text format.
+func (c *Canary) Metrics() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# HELP credhub_canary_up Whether every operation of the last cycle succeeded.\n")
+	fmt.Fprintf(&out, "# TYPE credhub_canary_up gauge\n")
+	fmt.Fprintf(&out, "credhub_canary_up %d\n", boolToInt(c.up))
+	fmt.Fprintf(&out, "# HELP credhub_canary_cycles_total Cycles run.\n")
+	fmt.Fprintf(&out, "# TYPE credhub_canary_cycles_total counter\n")
+	fmt.Fprintf(&out, "credhub_canary_cycles_total %d\n", c.cycles)
+
+	operations := c.operations()
+
+	fmt.Fprintf(&out, "# HELP credhub_canary_operations_total Operations run, by outcome.\n")
+	fmt.Fprintf(&out, "# TYPE credhub_canary_operations_total counter\n")
+	for _, operation := range operations {
+		stats := c.stats[operation]
+		fmt.Fprintf(&out, "credhub_canary_operations_total{operation=%q,outcome=\"success\"} %d\n", operation, stats.successes)
+		fmt.Fprintf(&out, "credhub_canary_operations_total{operation=%q,outcome=\"failure\"} %d\n", operation, stats.failures)
+	}
+
+	fmt.Fprintf(&out, "# HELP credhub_canary_last_success Whether the most recent run of the operation succeeded.\n")
+	fmt.Fprintf(&out, "# TYPE credhub_canary_last_success gauge\n")
+	for _, operation := range operations {
+		fmt.Fprintf(&out, "credhub_canary_last_success{operation=%q} %d\n", operation, boolToInt(c.stats[operation].last.Success))
+	}
+
+	fmt.Fprintf(&out, "# HELP credhub_canary_operation_duration_seconds Operation latency.\n")
+	fmt.Fprintf(&out, "# TYPE credhub_canary_operation_duration_seconds histogram\n")
+	for _, operation := range operations {
+		stats := c.stats[operation]
+		for i, bound := range LatencyBuckets {
+			fmt.Fprintf(&out, "credhub_canary_operation_duration_seconds_bucket{operation=%q,le=\"%g\"} %d\n", operation, bound, stats.buckets[i])
+		}
+		count := stats.successes + stats.failures
+		fmt.Fprintf(&out, "credhub_canary_operation_duration_seconds_bucket{operation=%q,le=\"+Inf\"} %d\n", operation, count)
+		fmt.Fprintf(&out, "credhub_canary_operation_duration_seconds_sum{operation=%q} %g\n", operation, stats.sum)
+		fmt.Fprintf(&out, "credhub_canary_operation_duration_seconds_count{operation=%q} %d\n", operation, count)
+	}
+
+	return out.String()
+}
+
+// operations returns the operations with statistics, in cycle order.
+func (c *Canary) operations() []string {
+	operations := []string{}
+	for _, operation := range Operations {
+		if _, ok := c.stats[operation]; ok {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// WriteJSON replaces jsonPath with the most recent results and a summary of
+// each operation.
+func (c *Canary) WriteJSON(jsonPath string) error {
+	c.lock.Lock()
+	type summary struct {
+		Successes   uint64 `json:"successes"`
+		Failures    uint64 `json:"failures"`
+		LastSuccess bool   `json:"last_success"`
+		LastLatency int64  `json:"last_latency_ns"`
+	}
+	report := struct {
+		Up         bool               `json:"up"`
+		Cycles     uint64             `json:"cycles"`
+		Operations map[string]summary `json:"operations"`
+		Recent     []Result           `json:"recent"`
+	}{Up: c.up, Cycles: c.cycles, Operations: map[string]summary{}, Recent: c.recent}
+	for operation, stats := range c.stats {
+		report.Operations[operation] = summary{
+			Successes:   stats.successes,
+			Failures:    stats.failures,
+			LastSuccess: stats.last.Success,
+			LastLatency: int64(stats.last.Latency),
+		}
+	}
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so readers never see a partial file.
+	temporary, err := ioutil.TempFile(filepath.Dir(jsonPath), filepath.Base(jsonPath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(encoded); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), jsonPath)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package canary_test
+
+import (
+	"testing"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var config Config
+
+func TestCanary(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, SuiteDescription("Canary Suite"))
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+
+	var err error
+	config, err = LoadConfig(IntegrationConfig...)
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
+
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
+}, func() {
+	StopSuite()
+})
@@ -0,0 +1,214 @@
+package canary_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/canary"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("the canary", func() {
+	var (
+		client     *CredhubClient
+		canaryPath string
+		c          *canary.Canary
+	)
+
+	BeforeEach(func() {
+		var err error
+		client, err = NewTokenClient(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		canaryPath = "/" + GenerateUniqueCredentialName()
+		c = canary.New(func() (*CredhubClient, error) { return client, nil }, canaryPath)
+	})
+
+	AfterEach(func() {
+		Expect(DeletePath(client, canaryPath)).To(BeEmpty())
+	})
+
+	It("runs every operation of a cycle successfully and leaves nothing behind", func() {
+		results := c.Cycle()
+
+		operations := []string{}
+		for _, result := range results {
+			Expect(result.Success).To(BeTrue(), "%s: %s", result.Operation, result.Error)
+			operations = append(operations, result.Operation)
+		}
+		Expect(operations).To(Equal([]string{"login", "set", "get", "generate", "regenerate", "find", "interpolate", "delete", "delete"}))
+
+		found, err := client.FindByPath(canaryPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeEmpty())
+	})
+
+	It("logs in once while its token is accepted", func() {
+		c.Cycle()
+		results := c.Cycle()
+
+		Expect(results[0].Operation).To(Equal("set"))
+	})
+
+	It("logs in again and retries the operation when CredHub rejects its client", func() {
+		rejected, err := NewFixtureClient(config, client_certs.MissingAppGuid())
+		Expect(err).NotTo(HaveOccurred())
+		logins := 0
+		c.Login = func() (*CredhubClient, error) {
+			logins++
+			if logins == 1 {
+				return rejected, nil
+			}
+			return client, nil
+		}
+
+		results := c.Cycle()
+
+		operations := []string{}
+		for _, result := range results {
+			Expect(result.Success).To(BeTrue(), "%s: %s", result.Operation, result.Error)
+			operations = append(operations, result.Operation)
+		}
+		Expect(operations).To(Equal([]string{"login", "login", "set", "get", "generate", "regenerate", "find", "interpolate", "delete", "delete"}))
+		Expect(c.Metrics()).To(ContainSubstring("credhub_canary_up 1\n"))
+	})
+
+	It("skips the rest of the cycle when CredHub rejects its client again", func() {
+		rejected, err := NewFixtureClient(config, client_certs.MissingAppGuid())
+		Expect(err).NotTo(HaveOccurred())
+		c.Login = func() (*CredhubClient, error) { return rejected, nil }
+
+		results := c.Cycle()
+
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Operation).To(Equal("login"))
+		Expect(results[1].Operation).To(Equal("login"))
+		Expect(results[2].Operation).To(Equal("set"))
+		Expect(results[2].Success).To(BeFalse())
+		Expect(c.Metrics()).To(ContainSubstring("credhub_canary_up 0\n"))
+	})
+
+	It("serves its results in the Prometheus text format", func() {
+		c.Cycle()
+		c.Cycle()
+
+		recorder := httptest.NewRecorder()
+		c.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		metrics := recorder.Body.String()
+		Expect(metrics).To(ContainSubstring("credhub_canary_up 1\n"))
+		Expect(metrics).To(ContainSubstring("credhub_canary_cycles_total 2\n"))
+		Expect(metrics).To(ContainSubstring(`credhub_canary_operations_total{operation="login",outcome="success"} 1` + "\n"))
+		Expect(metrics).To(ContainSubstring(`credhub_canary_operations_total{operation="get",outcome="success"} 2` + "\n"))
+		Expect(metrics).To(ContainSubstring(`credhub_canary_operations_total{operation="delete",outcome="failure"} 0` + "\n"))
+		Expect(metrics).To(ContainSubstring(`credhub_canary_operation_duration_seconds_bucket{operation="set",le="+Inf"} 2` + "\n"))
+		Expect(metrics).To(ContainSubstring(`credhub_canary_operation_duration_seconds_count{operation="delete"} 4` + "\n"))
+	})
+
+	It("writes its most recent results to a JSON file", func() {
+		dir, err := ioutil.TempDir("", "canary")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		jsonPath := filepath.Join(dir, "canary.json")
+
+		c.Window = 5
+		c.Cycle()
+		Expect(c.WriteJSON(jsonPath)).To(Succeed())
+
+		contents, err := ioutil.ReadFile(jsonPath)
+		Expect(err).NotTo(HaveOccurred())
+		report := struct {
+			Up         bool
+			Cycles     int
+			Operations map[string]struct{ Successes int }
+			Recent     []canary.Result
+		}{}
+		Expect(json.Unmarshal(contents, &report)).To(Succeed())
+
+		Expect(report.Up).To(BeTrue())
+		Expect(report.Cycles).To(Equal(1))
+		Expect(report.Operations["delete"].Successes).To(Equal(2))
+		Expect(report.Recent).To(HaveLen(5))
+		Expect(report.Recent[4].Operation).To(Equal("delete"))
+
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("the run_canary command", func() {
+	var (
+		client     *CredhubClient
+		canaryPath string
+		dir        string
+	)
+
+	BeforeEach(func() {
+		var err error
+		client, err = NewTokenClient(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		canaryPath = "/" + GenerateUniqueCredentialName()
+		dir, err = ioutil.TempDir("", "run-canary")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		Expect(DeletePath(client, canaryPath)).To(BeEmpty())
+	})
+
+	// The command runs cycles outside any Ginkgo suite, where nothing handles
+	// a failed Gomega assertion, so this catches the cycle relying on one.
+	It("runs cycles successfully outside a Ginkgo suite", func() {
+		binary, err := gexec.Build("github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/canary/run_canary")
+		Expect(err).NotTo(HaveOccurred())
+		defer gexec.CleanupBuildArtifacts()
+
+		jsonPath := filepath.Join(dir, "canary.json")
+		command := exec.Command(binary, "-interval", "100ms", "-listen", "", "-json", jsonPath, "-path", canaryPath)
+		command.Dir = dir
+		command.Env = append(os.Environ(),
+			"PWD="+dir,
+			"CREDHUB_API_URL="+config.ApiUrl,
+			"CREDHUB_API_USERNAME="+config.ApiUsername,
+			"CREDHUB_API_PASSWORD="+config.ApiPassword,
+			"CREDHUB_CREDENTIAL_ROOT="+config.CredentialRoot,
+			"CREDHUB_UAA_CA="+config.UAACa,
+		)
+		session, err := gexec.Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { session.Terminate().Wait() }()
+
+		report := struct {
+			Up     bool
+			Cycles int
+			Recent []canary.Result
+		}{}
+		Eventually(func() int {
+			contents, err := ioutil.ReadFile(jsonPath)
+			if err != nil {
+				return 0
+			}
+			Expect(json.Unmarshal(contents, &report)).To(Succeed())
+			return report.Cycles
+		}, 30*time.Second).Should(BeNumerically(">=", 2))
+		Expect(session).NotTo(gexec.Exit())
+
+		for _, result := range report.Recent {
+			Expect(result.Success).To(BeTrue(), "%s: %s", result.Operation, result.Error)
+		}
+		Expect(report.Up).To(BeTrue())
+	})
+})
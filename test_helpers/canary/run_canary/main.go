@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/canary"
+)
+
+func main() {
+	var interval time.Duration
+	var listen, jsonPath, credentialPath string
+	var window int
+
+	flag.DurationVar(&interval, "interval", 30*time.Second, "time between cycles")
+	flag.StringVar(&listen, "listen", "127.0.0.1:9391", "address to serve Prometheus metrics on at /metrics, or empty to not serve them")
+	flag.StringVar(&jsonPath, "json", "canary.json", "file to write the recent results to after every cycle, or empty to not write one")
+	flag.StringVar(&credentialPath, "path", "", "path to write the canary's credentials under (default /canary/<hostname>)")
+	flag.IntVar(&window, "window", 100, "number of recent results to keep in the JSON file")
+	flag.Parse()
+
+	cfg, err := test_helpers.LoadConfig(test_helpers.SmokeConfig...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if credentialPath == "" {
+		hostname, _ := os.Hostname()
+		credentialPath = path.Join("/canary", hostname)
+	}
+
+	c := canary.New(func() (*test_helpers.CredhubClient, error) {
+		// Like the smoke suite, the canary only needs credhub_ca_cert.pem
+		// when a credential root is configured.
+		if cfg.CredentialRoot == "" {
+			return test_helpers.NewTokenClientSkipTls(cfg)
+		}
+		return test_helpers.NewTokenClient(cfg)
+	}, credentialPath)
+	c.Window = window
+
+	if listen != "" {
+		http.Handle("/metrics", c)
+		go func() {
+			log.Fatal(http.ListenAndServe(listen, nil))
+		}()
+		fmt.Printf("Serving metrics on http://%s/metrics\n", listen)
+	}
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	fmt.Printf("Checking %s every %s\n", cfg.ApiUrl, interval)
+	c.Run(interval, jsonPath, stop)
+}
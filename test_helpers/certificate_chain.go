@@ -0,0 +1,140 @@
+package test_helpers
+
+import (
+	"crypto/x509"
+	"fmt"
+	"path"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/matchers"
+	. "github.com/onsi/gomega"
+)
+
+// ChainDepths are the numbers of intermediate CAs the chain specs generate
+// between the root and the leaf.
+var ChainDepths = []int{0, 1, 2, 4}
+
+// ChainLink is one certificate of a generated hierarchy.
+type ChainLink struct {
+	Name       string
+	CommonName string
+
+	// CaName is the CA that signs the certificate, or empty for the
+	// self-signed root.
+	CaName string
+	IsCa   bool
+}
+
+// ChainLinks returns, root first, the certificates of a hierarchy under base
+// with the given number of intermediate CAs between the root and the leaf.
+func ChainLinks(base string, intermediates int) []ChainLink {
+	root := ChainLink{Name: path.Join(base, "root"), CommonName: "chain root", IsCa: true}
+	links := []ChainLink{root}
+	for i := 1; i <= intermediates; i++ {
+		links = append(links, ChainLink{
+			Name:       path.Join(base, fmt.Sprintf("intermediate-%d", i)),
+			CommonName: fmt.Sprintf("chain intermediate %d", i),
+			CaName:     links[i-1].Name,
+			IsCa:       true,
+		})
+	}
+	return append(links, ChainLink{
+		Name:       path.Join(base, "leaf"),
+		CommonName: "chain leaf",
+		CaName:     links[len(links)-1].Name,
+	})
+}
+
+// Flags returns the CLI flags for the link, after -t certificate.
+func (l ChainLink) Flags() []string {
+	flags := []string{"--common-name", l.CommonName}
+	if l.IsCa {
+		flags = append(flags, "--is-ca")
+	}
+	if l.CaName == "" {
+		return append(flags, "--self-sign")
+	}
+	return append(flags, "--ca", l.CaName)
+}
+
+// Parameters returns the API generation parameters for the link.
+func (l ChainLink) Parameters() map[string]interface{} {
+	parameters := map[string]interface{}{"common_name": l.CommonName}
+	if l.IsCa {
+		parameters["is_ca"] = true
+	}
+	if l.CaName == "" {
+		parameters["self_sign"] = true
+	} else {
+		parameters["ca"] = l.CaName
+	}
+	return parameters
+}
+
+// ExpectChainVerifies checks a hierarchy generated from ChainLinks, root
+// first. The leaf and every intermediate must verify with x509.Verify against
+// the root, using only the certificates returned in the ca fields, and each
+// certificate must be linked to its issuer by name, key id and signature.
+func ExpectChainVerifies(chain []CertificateCredential) {
+	certs := []*x509.Certificate{}
+	for _, credential := range chain {
+		certs = append(certs, credential.Certificate())
+	}
+	root, leaf := certs[0], certs[len(certs)-1]
+
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	roots.AddCert(chain[0].Ca())
+	for _, credential := range chain[1:] {
+		if ca := credential.Ca(); !ca.Equal(root) {
+			intermediates.AddCert(ca)
+		}
+	}
+
+	for i, cert := range certs {
+		verified, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		ExpectWithOffset(1, err).NotTo(HaveOccurred(), "verifying %q", cert.Subject.CommonName)
+		ExpectWithOffset(1, verified).To(HaveLen(1), "chains for %q", cert.Subject.CommonName)
+		ExpectWithOffset(1, verified[0]).To(HaveLen(i+1), "length of the chain for %q", cert.Subject.CommonName)
+		for j, link := range verified[0] {
+			ExpectWithOffset(1, link.Equal(certs[i-j])).To(BeTrue(),
+				"certificate %d of the chain for %q is %q, expected %q", j, cert.Subject.CommonName, link.Subject.CommonName, certs[i-j].Subject.CommonName)
+		}
+	}
+
+	ExpectWithOffset(1, root.RawIssuer).To(Equal(root.RawSubject), "root issuer")
+	ExpectWithOffset(1, root).To(matchers.BeSignedBy(root))
+	if len(root.AuthorityKeyId) > 0 {
+		ExpectWithOffset(1, root.AuthorityKeyId).To(Equal(root.SubjectKeyId), "root authority key id")
+	}
+	ExpectWithOffset(1, chain[0].Ca().Equal(root)).To(BeTrue(), "the root's ca field is not the root")
+
+	for i, cert := range certs[1:] {
+		issuer := certs[i]
+		ExpectWithOffset(1, cert.RawIssuer).To(Equal(issuer.RawSubject), "issuer of %q", cert.Subject.CommonName)
+		ExpectWithOffset(1, cert.AuthorityKeyId).To(Equal(issuer.SubjectKeyId), "authority key id of %q", cert.Subject.CommonName)
+		ExpectWithOffset(1, cert.CheckSignatureFrom(issuer)).To(Succeed(), "signature of %q", cert.Subject.CommonName)
+		ExpectWithOffset(1, chain[i+1].Ca().Equal(issuer)).To(BeTrue(), "the ca field of %q is not its issuer", cert.Subject.CommonName)
+	}
+
+	// CredHub puts basic constraints on every certificate it generates, and no
+	// path length constraint on its CAs, which x509 parses as a MaxPathLen of
+	// -1. Chains of any depth therefore verify.
+	for _, ca := range certs[:len(certs)-1] {
+		ExpectWithOffset(1, ca.SubjectKeyId).NotTo(BeEmpty(), "subject key id of %q", ca.Subject.CommonName)
+		ExpectWithOffset(1, ca).To(matchers.BeCA())
+		ExpectWithOffset(1, ca.BasicConstraintsValid).To(BeTrue(), "basic constraints of %q", ca.Subject.CommonName)
+		ExpectWithOffset(1, ca.MaxPathLen).To(Equal(-1), "path length constraint of %q", ca.Subject.CommonName)
+		ExpectWithOffset(1, ca.MaxPathLenZero).To(BeFalse(), "path length constraint of %q", ca.Subject.CommonName)
+		ExpectWithOffset(1, ca.KeyUsage&x509.KeyUsageCertSign).NotTo(BeZero(), "%q is missing key_cert_sign", ca.Subject.CommonName)
+		ExpectWithOffset(1, ca.KeyUsage&x509.KeyUsageCRLSign).NotTo(BeZero(), "%q is missing crl_sign", ca.Subject.CommonName)
+	}
+
+	ExpectWithOffset(1, leaf).NotTo(matchers.BeCA())
+	ExpectWithOffset(1, leaf.BasicConstraintsValid).To(BeTrue(), "basic constraints of the leaf")
+	ExpectWithOffset(1, leaf.MaxPathLen).To(Equal(-1), "path length constraint of the leaf")
+	ExpectWithOffset(1, leaf.MaxPathLenZero).To(BeFalse(), "path length constraint of the leaf")
+	ExpectWithOffset(1, leaf.SubjectKeyId).NotTo(BeEmpty(), "subject key id of the leaf")
+}
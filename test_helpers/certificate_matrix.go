@@ -0,0 +1,249 @@
+package test_helpers
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/matchers"
+	. "github.com/onsi/gomega"
+)
+
+// Common names of the certificates the matrix specs generate.
+const (
+	MatrixCommonName   = "matrix.example.com"
+	MatrixCaCommonName = "matrix-ca.example.com"
+)
+
+// CertificateCase is a set of certificate generation parameters. Zero fields
+// are left for CredHub to default.
+type CertificateCase struct {
+	Description      string
+	KeyLength        int
+	KeyUsage         []string
+	ExtendedKeyUsage []string
+	AlternativeNames []string
+	Organization     string
+	OrganizationUnit string
+	Locality         string
+	State            string
+	Country          string
+	Duration         int
+}
+
+// CertificateCases varies one parameter at a time through every key length,
+// key usage and extended key usage CredHub supports, DNS and IP alternative
+// names, each subject field and a range of durations.
+var CertificateCases = certificateCases()
+
+func certificateCases() []CertificateCase {
+	cases := []CertificateCase{{Description: "the default parameters"}}
+
+	for _, keyLength := range []int{2048, 3072, 4096} {
+		cases = append(cases, CertificateCase{Description: fmt.Sprintf("a %d bit key", keyLength), KeyLength: keyLength})
+	}
+
+	allKeyUsages := []string{}
+	for _, usage := range matchers.KeyUsageNames {
+		cases = append(cases, CertificateCase{Description: "key usage " + usage.Name, KeyUsage: []string{usage.Name}})
+		allKeyUsages = append(allKeyUsages, usage.Name)
+	}
+	cases = append(cases, CertificateCase{Description: "every key usage", KeyUsage: allKeyUsages})
+
+	allExtKeyUsages := []string{}
+	for _, usage := range matchers.ExtKeyUsageNames {
+		cases = append(cases, CertificateCase{Description: "extended key usage " + usage.Name, ExtendedKeyUsage: []string{usage.Name}})
+		allExtKeyUsages = append(allExtKeyUsages, usage.Name)
+	}
+	cases = append(cases, CertificateCase{Description: "every extended key usage", ExtendedKeyUsage: allExtKeyUsages})
+
+	cases = append(cases,
+		CertificateCase{Description: "a DNS name", AlternativeNames: []string{"example.com"}},
+		CertificateCase{Description: "a wildcard DNS name", AlternativeNames: []string{"*.example.com"}},
+		CertificateCase{Description: "an IPv4 address", AlternativeNames: []string{"10.0.0.1"}},
+		CertificateCase{Description: "an IPv6 address", AlternativeNames: []string{"2001:db8::1"}},
+		CertificateCase{Description: "DNS names and IP addresses", AlternativeNames: []string{"example.com", "www.example.com", "10.0.0.1", "2001:db8::1"}},
+
+		CertificateCase{Description: "an organization", Organization: "Cloud Foundry"},
+		CertificateCase{Description: "an organization unit", OrganizationUnit: "CredHub"},
+		CertificateCase{Description: "a locality", Locality: "San Francisco"},
+		CertificateCase{Description: "a state", State: "California"},
+		CertificateCase{Description: "a country", Country: "US"},
+		CertificateCase{
+			Description:      "every subject field",
+			Organization:     "Cloud Foundry",
+			OrganizationUnit: "CredHub",
+			Locality:         "San Francisco",
+			State:            "California",
+			Country:          "US",
+		},
+	)
+
+	for _, duration := range []int{1, 30, 90, 3650} {
+		cases = append(cases, CertificateCase{Description: fmt.Sprintf("a duration of %d days", duration), Duration: duration})
+	}
+
+	return cases
+}
+
+// Flags returns the CLI flags for the case, after -t certificate.
+func (c CertificateCase) Flags() []string {
+	flags := []string{"--common-name", MatrixCommonName}
+	if c.KeyLength != 0 {
+		flags = append(flags, "--key-length", strconv.Itoa(c.KeyLength))
+	}
+	for _, usage := range c.KeyUsage {
+		flags = append(flags, "--key-usage", usage)
+	}
+	for _, usage := range c.ExtendedKeyUsage {
+		flags = append(flags, "--ext-key-usage", usage)
+	}
+	for _, name := range c.AlternativeNames {
+		flags = append(flags, "--alternative-name", name)
+	}
+	for _, field := range []struct{ flag, value string }{
+		{"--organization", c.Organization},
+		{"--organization-unit", c.OrganizationUnit},
+		{"--locality", c.Locality},
+		{"--state", c.State},
+		{"--country", c.Country},
+	} {
+		if field.value != "" {
+			flags = append(flags, field.flag, field.value)
+		}
+	}
+	if c.Duration != 0 {
+		flags = append(flags, "--duration", strconv.Itoa(c.Duration))
+	}
+	return flags
+}
+
+// Parameters returns the API generation parameters for the case.
+func (c CertificateCase) Parameters() map[string]interface{} {
+	parameters := map[string]interface{}{"common_name": MatrixCommonName}
+	for key, value := range map[string]interface{}{
+		"key_length":         c.KeyLength,
+		"key_usage":          c.KeyUsage,
+		"extended_key_usage": c.ExtendedKeyUsage,
+		"alternative_names":  c.AlternativeNames,
+		"organization":       c.Organization,
+		"organization_unit":  c.OrganizationUnit,
+		"locality":           c.Locality,
+		"state":              c.State,
+		"country":            c.Country,
+		"duration":           c.Duration,
+	} {
+		switch typed := value.(type) {
+		case int:
+			if typed != 0 {
+				parameters[key] = typed
+			}
+		case string:
+			if typed != "" {
+				parameters[key] = typed
+			}
+		case []string:
+			if len(typed) > 0 {
+				parameters[key] = typed
+			}
+		}
+	}
+	return parameters
+}
+
+// Subject is the subject the generated certificate should have.
+func (c CertificateCase) Subject() pkix.Name {
+	subject := pkix.Name{CommonName: MatrixCommonName}
+	for _, field := range []struct {
+		value  string
+		target *[]string
+	}{
+		{c.Organization, &subject.Organization},
+		{c.OrganizationUnit, &subject.OrganizationalUnit},
+		{c.Locality, &subject.Locality},
+		{c.State, &subject.Province},
+		{c.Country, &subject.Country},
+	} {
+		if field.value != "" {
+			*field.target = []string{field.value}
+		}
+	}
+	return subject
+}
+
+// ExpectCertificateMatches checks every field of a certificate generated from
+// the case. ca is the CA credential it was generated with, or nil when it was
+// self-signed.
+func ExpectCertificateMatches(credential CertificateCredential, c CertificateCase, ca *CertificateCredential) {
+	cert := credential.Certificate()
+	privateKey := credential.PrivateKey()
+
+	keyLength, duration := c.KeyLength, c.Duration
+	if keyLength == 0 {
+		keyLength = 2048
+	}
+	if duration == 0 {
+		duration = 365
+	}
+
+	var keyUsage x509.KeyUsage
+	for _, name := range c.KeyUsage {
+		for _, known := range matchers.KeyUsageNames {
+			if known.Name == name {
+				keyUsage |= known.Usage
+			}
+		}
+	}
+	extKeyUsage := []x509.ExtKeyUsage{}
+	for _, name := range c.ExtendedKeyUsage {
+		for _, known := range matchers.ExtKeyUsageNames {
+			if known.Name == name {
+				extKeyUsage = append(extKeyUsage, known.Usage)
+			}
+		}
+	}
+	dnsNames, ipAddresses := []string{}, []string{}
+	for _, name := range c.AlternativeNames {
+		if net.ParseIP(name) != nil {
+			ipAddresses = append(ipAddresses, name)
+		} else {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+
+	ExpectWithOffset(1, cert.Version).To(Equal(3), "version")
+	ExpectWithOffset(1, cert.SerialNumber.Sign()).To(Equal(1), "serial number")
+	ExpectWithOffset(1, cert.SignatureAlgorithm).To(Equal(x509.SHA256WithRSA), "signature algorithm")
+	ExpectWithOffset(1, cert).To(matchers.HaveSubject(c.Subject()))
+	ExpectWithOffset(1, cert).NotTo(matchers.BeCA())
+	ExpectWithOffset(1, cert).To(matchers.HaveKeyUsage(keyUsage))
+	ExpectWithOffset(1, cert).To(matchers.HaveExtKeyUsage(extKeyUsage...))
+	ExpectWithOffset(1, cert).To(matchers.HaveDNSNames(dnsNames...))
+	ExpectWithOffset(1, cert).To(matchers.HaveIPAddresses(ipAddresses...))
+	ExpectWithOffset(1, cert.EmailAddresses).To(BeEmpty(), "email addresses")
+	ExpectWithOffset(1, cert.URIs).To(BeEmpty(), "URIs")
+	ExpectWithOffset(1, cert).To(matchers.HaveValidityDays(duration))
+	ExpectWithOffset(1, cert.NotBefore).To(BeTemporally("~", time.Now(), time.Hour), "not before")
+	ExpectWithOffset(1, cert).To(matchers.HaveKeyBits(keyLength))
+	ExpectWithOffset(1, privateKey.N).To(Equal(cert.PublicKey.(*rsa.PublicKey).N), "private key")
+	ExpectWithOffset(1, cert.SubjectKeyId).NotTo(BeEmpty(), "subject key id")
+
+	if ca == nil {
+		ExpectWithOffset(1, cert.RawIssuer).To(Equal(cert.RawSubject), "issuer")
+		ExpectWithOffset(1, cert).To(matchers.BeSignedBy(cert))
+		if len(cert.AuthorityKeyId) > 0 {
+			ExpectWithOffset(1, cert.AuthorityKeyId).To(Equal(cert.SubjectKeyId), "authority key id")
+		}
+		return
+	}
+
+	caCert := ca.Certificate()
+	ExpectWithOffset(1, cert.RawIssuer).To(Equal(caCert.RawSubject), "issuer")
+	ExpectWithOffset(1, cert).To(matchers.BeSignedBy(caCert))
+	ExpectWithOffset(1, cert.AuthorityKeyId).To(Equal(caCert.SubjectKeyId), "authority key id")
+	ExpectWithOffset(1, credential.Value.Ca).To(Equal(ca.Value.Certificate), "ca")
+}
@@ -0,0 +1,240 @@
+package test_helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	"gopkg.in/yaml.v2"
+)
+
+// Credentials created by RunCommand (set, generate and import) and by
+// CredhubClient.Set and Generate are recorded here, so that suites can delete
+// them with CleanupCredentials in an AfterEach.
+var createdCredentials = &credentialRegistry{}
+
+type trackedCredential struct {
+	name   string
+	delete func(name string) error
+}
+
+type credentialRegistry struct {
+	sync.Mutex
+	tracked  []trackedCredential
+	leftover []string
+}
+
+// TrackCredential records a credential for CleanupCredentials to remove with
+// the given delete function. Tracking a name again replaces its delete function.
+func TrackCredential(name string, delete func(name string) error) {
+	createdCredentials.track(name, delete)
+}
+
+// UntrackCredential forgets a credential that the spec deleted itself.
+func UntrackCredential(name string) {
+	createdCredentials.untrack(name)
+}
+
+// CleanupCredentials deletes every tracked credential, newest first, so that
+// certificates go before the CAs that signed them. Credentials that could not
+// be deleted are kept for ReportCleanup.
+func CleanupCredentials() {
+	createdCredentials.Lock()
+	tracked := createdCredentials.tracked
+	createdCredentials.tracked = nil
+	createdCredentials.Unlock()
+
+	for i := len(tracked) - 1; i >= 0; i-- {
+		if err := tracked[i].delete(tracked[i].name); err != nil {
+			fmt.Fprintf(GinkgoWriter, "failed to clean up %s: %s\n", tracked[i].name, err)
+
+			createdCredentials.Lock()
+			createdCredentials.leftover = append(createdCredentials.leftover, fmt.Sprintf("%s: %s", tracked[i].name, err))
+			createdCredentials.Unlock()
+		}
+	}
+}
+
+// ReportCleanup prints the credentials CleanupCredentials could not delete on
+// this node, for the SynchronizedAfterSuite, and returns them.
+func ReportCleanup() []string {
+	createdCredentials.Lock()
+	defer createdCredentials.Unlock()
+
+	leftover := createdCredentials.leftover
+	createdCredentials.leftover = nil
+	for _, credential := range createdCredentials.tracked {
+		leftover = append(leftover, credential.name+": never cleaned up")
+	}
+
+	printLeftover(fmt.Sprintf("on node %d", GinkgoParallelNode()), leftover)
+	return leftover
+}
+
+// CleanupRun deletes whatever this run left under RunPath, from any node, and
+// reports what it could not delete. Call it from the last function of a
+// SynchronizedAfterSuite.
+func CleanupRun(client *CredhubClient) []string {
+	return CleanupPath(client, RunPath())
+}
+
+// CleanupNode deletes whatever this node left under NodePath and reports what
+// it could not delete. Suites whose nodes each authenticate as their own actor,
+// and so cannot delete each other's credentials, call it from the first
+// function of a SynchronizedAfterSuite, which runs on every node, instead of
+// CleanupRun.
+func CleanupNode(client *CredhubClient) []string {
+	return CleanupPath(client, NodePath())
+}
+
+// CleanupPath deletes every credential under credentialPath and reports the
+// ones it could not delete.
+func CleanupPath(client *CredhubClient, credentialPath string) []string {
+	leftover := []string{}
+	for _, err := range DeletePath(client, credentialPath) {
+		leftover = append(leftover, err.Error())
+	}
+
+	printLeftover("under "+credentialPath, leftover)
+	return leftover
+}
+
+// CredentialError is a failure to delete one credential, or to find the
+// credentials under a path.
+type CredentialError struct {
+	Name string
+	Err  error
+}
+
+func (e *CredentialError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Err)
+}
+
+// DeletePath deletes every credential under credentialPath, at any depth, and
+// returns a *CredentialError for each one it could not delete. Names are only
+// ever passed to the API, so whitespace and shell metacharacters in them are
+// safe.
+func DeletePath(client *CredhubClient, credentialPath string) []error {
+	found, err := client.FindByPath(credentialPath)
+	if err != nil {
+		return []error{&CredentialError{Name: credentialPath, Err: err}}
+	}
+
+	errs := []error{}
+	for _, credential := range found {
+		if err := client.Delete(credential.Name); err != nil && !IsNotFound(err) {
+			errs = append(errs, &CredentialError{Name: credential.Name, Err: err})
+		}
+	}
+	return errs
+}
+
+func printLeftover(where string, leftover []string) {
+	if len(leftover) > 0 {
+		fmt.Fprintf(os.Stdout, "\nCould not clean up %d credential(s) %s:\n  %s\n",
+			len(leftover), where, strings.Join(leftover, "\n  "))
+	}
+}
+
+func (r *credentialRegistry) track(name string, delete func(string) error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.remove(name)
+	r.tracked = append(r.tracked, trackedCredential{name: absoluteName(name), delete: delete})
+}
+
+func (r *credentialRegistry) untrack(name string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.remove(name)
+}
+
+func (r *credentialRegistry) remove(name string) {
+	name = absoluteName(name)
+	for i, credential := range r.tracked {
+		if credential.name == name {
+			r.tracked = append(r.tracked[:i], r.tracked[i+1:]...)
+			return
+		}
+	}
+}
+
+// trackCommand records or forgets the credentials named by a CLI command that
+// succeeded.
+func trackCommand(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	switch args[0] {
+	case "set", "generate":
+		if name, ok := commandFlag(args, "-n", "--name"); ok {
+			TrackCredential(name, deleteWithCommand)
+		}
+	case "import":
+		if file, ok := commandFlag(args, "-f", "--file"); ok {
+			for _, name := range importedNames(file) {
+				TrackCredential(name, deleteWithCommand)
+			}
+		}
+	case "delete":
+		if name, ok := commandFlag(args, "-n", "--name"); ok {
+			UntrackCredential(name)
+		}
+	}
+}
+
+func deleteWithCommand(name string) error {
+	session := RunCommand("delete", "-n", name)
+	stdErr := strings.TrimSpace(string(session.Err.Contents()))
+	if session.ExitCode() == 0 || strings.Contains(stdErr, "does not exist") {
+		return nil
+	}
+	return fmt.Errorf("credhub delete exited %d: %s", session.ExitCode(), stdErr)
+}
+
+func commandFlag(args []string, short, long string) (string, bool) {
+	for i, arg := range args {
+		switch {
+		case (arg == short || arg == long) && i+1 < len(args):
+			return args[i+1], true
+		case strings.HasPrefix(arg, long+"="):
+			return strings.TrimPrefix(arg, long+"="), true
+		}
+	}
+	return "", false
+}
+
+func importedNames(file string) []string {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	imported := struct {
+		Credentials []struct {
+			Name string `yaml:"name"`
+		} `yaml:"credentials"`
+	}{}
+	if yaml.Unmarshal(contents, &imported) != nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, credential := range imported.Credentials {
+		names = append(names, credential.Name)
+	}
+	return names
+}
+
+func absoluteName(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/" + name
+}
@@ -0,0 +1,39 @@
+package test_helpers_test
+
+import (
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cleaning up a path", func() {
+	var client *CredhubClient
+
+	BeforeEach(func() {
+		config, err := LoadConfig(IntegrationConfig...)
+		Expect(err).NotTo(HaveOccurred())
+		client, err = NewTokenClient(config)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("deletes credentials whose names contain whitespace and shell metacharacters", func() {
+		specPath := "/" + GenerateUniqueCredentialName()
+		name := path.Join(specPath, "a b", "$(x);'c")
+		_, err := client.Set(name, "password", "some-password", true)
+		Expect(err).NotTo(HaveOccurred())
+
+		found, err := client.FindByPath(specPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(FoundNames(found)).To(Equal([]string{name}))
+
+		Expect(DeletePath(client, specPath)).To(BeEmpty())
+
+		found, err = client.FindByPath(specPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeEmpty())
+		_, err = client.GetByName(name)
+		Expect(IsNotFound(err)).To(BeTrue(), "getting %s: %v", name, err)
+	})
+})
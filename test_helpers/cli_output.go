@@ -0,0 +1,102 @@
+package test_helpers
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// RunCredentialCommand runs a CLI command that prints one credential (get,
+// set, generate, regenerate) with JSON output, expects it to succeed and
+// decodes the credential it printed.
+func RunCredentialCommand(args ...string) Credential {
+	session := RunCommand(append(args, "--output-json")...)
+	EventuallyWithOffset(1, session).Should(Exit(0))
+
+	return ParseCredential(session.Out.Contents())
+}
+
+// RunFindCommand runs `credhub find` with JSON output and returns the credentials it found.
+func RunFindCommand(args ...string) []FoundCredential {
+	session := RunCommand(append(append([]string{"find"}, args...), "--output-json")...)
+	EventuallyWithOffset(1, session).Should(Exit(0))
+
+	found := struct {
+		Credentials []FoundCredential `json:"credentials"`
+	}{}
+	ExpectWithOffset(1, json.Unmarshal(session.Out.Contents(), &found)).To(Succeed())
+	return found.Credentials
+}
+
+func ParseCredential(output []byte) Credential {
+	credential := Credential{}
+	ExpectWithOffset(1, json.Unmarshal(output, &credential)).To(Succeed(), "CLI output was not a JSON credential:\n%s", output)
+	return credential
+}
+
+// Certificate parses the certificate value.
+func (c CertificateCredential) Certificate() *x509.Certificate {
+	return ParseCertificate(c.Value.Certificate)
+}
+
+// Ca parses the CA the certificate was signed with.
+func (c CertificateCredential) Ca() *x509.Certificate {
+	return ParseCertificate(c.Value.Ca)
+}
+
+func (c CertificateCredential) PrivateKey() *rsa.PrivateKey {
+	return ParsePrivateKey(c.Value.PrivateKey)
+}
+
+func (c RSACredential) PublicKey() *rsa.PublicKey {
+	return ParsePublicKey(c.Value.PublicKey)
+}
+
+func (c RSACredential) PrivateKey() *rsa.PrivateKey {
+	return ParsePrivateKey(c.Value.PrivateKey)
+}
+
+func (c SSHCredential) PrivateKey() *rsa.PrivateKey {
+	return ParsePrivateKey(c.Value.PrivateKey)
+}
+
+// https://golang.org/pkg/crypto/x509/#Certificate
+func ParseCertificate(pemCert string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(pemCert))
+	if block == nil {
+		panic("failed to parse certificate PEM")
+	}
+	parsed_cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		panic("failed to parse certificate: " + err.Error())
+	}
+	return parsed_cert
+}
+
+func ParsePublicKey(pemKey string) *rsa.PublicKey {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		panic("failed to parse public key PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		panic("failed to parse public key: " + err.Error())
+	}
+	return key.(*rsa.PublicKey)
+}
+
+func ParsePrivateKey(pemKey string) *rsa.PrivateKey {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		panic("failed to parse private key PEM")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		panic("failed to parse private key: " + err.Error())
+	}
+	return key
+}
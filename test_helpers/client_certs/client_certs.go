@@ -0,0 +1,282 @@
+// Package client_certs generates the client certificates the mutual TLS specs
+// authenticate with: a valid one signed by CredHub's client CA, and variants
+// that CredHub should reject.
+package client_certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	DefaultCommonName = "credhub_test_client"
+	defaultLifetime   = 30 * 24 * time.Hour
+)
+
+type KeyType int
+
+const (
+	RSA2048 KeyType = iota
+	ECDSAP256
+)
+
+// Authority signs client certificates.
+type Authority struct {
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+}
+
+// Fixture describes a client certificate. The zero value is a valid client
+// certificate for a random application.
+type Fixture struct {
+	CommonName string
+
+	// AppGuid is put in the subject as OU=app:<AppGuid>, which is how CredHub
+	// identifies applications. A random GUID is used when it is empty.
+	AppGuid     string
+	OmitAppGuid bool
+
+	// NotBefore and NotAfter default to a minute ago and 30 days from now.
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	// ExtKeyUsage defaults to client authentication.
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	KeyType KeyType
+
+	// SelfSigned ignores the authority the fixture is issued by.
+	SelfSigned bool
+
+	// Issuer, when set, signs the fixture instead of the authority it is
+	// issued by.
+	Issuer *Authority
+}
+
+// Valid is a client certificate CredHub accepts.
+func Valid() Fixture {
+	return Fixture{}
+}
+
+// ForApp is a valid client certificate identifying the given application.
+func ForApp(appGuid string) Fixture {
+	return Fixture{AppGuid: appGuid}
+}
+
+func Expired() Fixture {
+	now := time.Now()
+	return Fixture{NotBefore: now.Add(-defaultLifetime), NotAfter: now.Add(-24 * time.Hour)}
+}
+
+func NotYetValid() Fixture {
+	now := time.Now()
+	return Fixture{NotBefore: now.Add(24 * time.Hour), NotAfter: now.Add(defaultLifetime)}
+}
+
+func SelfSigned() Fixture {
+	return Fixture{SelfSigned: true}
+}
+
+// UnknownCA is signed by a new CA that CredHub does not trust.
+func UnknownCA() (Fixture, error) {
+	unknown, err := NewAuthority("credhub_client_ca")
+	if err != nil {
+		return Fixture{}, err
+	}
+	return Fixture{Issuer: unknown}, nil
+}
+
+// WrongExtKeyUsage may only be used by servers.
+func WrongExtKeyUsage() Fixture {
+	return Fixture{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+}
+
+// MissingAppGuid has no OU=app:<guid> to identify an application by.
+func MissingAppGuid() Fixture {
+	return Fixture{OmitAppGuid: true}
+}
+
+// WrongKeyType has an ECDSA key instead of an RSA one.
+func WrongKeyType() Fixture {
+	return Fixture{KeyType: ECDSAP256}
+}
+
+// LoadAuthority reads a CA certificate and its private key from PEM files,
+// such as client_ca_cert.pem and client_ca_private.pem in the credential root.
+func LoadAuthority(certPath, keyPath string) (*Authority, error) {
+	keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain a signing key", keyPath)
+	}
+	return &Authority{Certificate: certificate, Key: key}, nil
+}
+
+// NewAuthority creates a self-signed CA.
+func NewAuthority(commonName string) (*Authority, error) {
+	key, err := generateKey(RSA2048)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          randomSerialNumber(),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Authority{Certificate: certificate, Key: key}, nil
+}
+
+// Issue returns the PEM encoded certificate and private key for fixture.
+func (a *Authority) Issue(fixture Fixture) (certificatePem, keyPem []byte, err error) {
+	key, err := generateKey(fixture.KeyType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := fixture.template()
+	parent, signer := template, crypto.Signer(key)
+	if !fixture.SelfSigned {
+		issuer := a
+		if fixture.Issuer != nil {
+			issuer = fixture.Issuer
+		}
+		parent, signer = issuer.Certificate, issuer.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certificatePem = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	return certificatePem, keyPem, nil
+}
+
+// Write issues fixture and writes it to <name>.pem and <name>_key.pem in dir.
+func (a *Authority) Write(dir, name string, fixture Fixture) (certificatePath, keyPath string, err error) {
+	certificatePem, keyPem, err := a.Issue(fixture)
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+
+	certificatePath = path.Join(dir, name+".pem")
+	keyPath = path.Join(dir, name+"_key.pem")
+	if err := ioutil.WriteFile(certificatePath, certificatePem, 0600); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(keyPath, keyPem, 0600); err != nil {
+		return "", "", err
+	}
+	return certificatePath, keyPath, nil
+}
+
+func (f Fixture) template() *x509.Certificate {
+	commonName := f.CommonName
+	if commonName == "" {
+		commonName = DefaultCommonName
+	}
+	subject := pkix.Name{CommonName: commonName}
+	if !f.OmitAppGuid {
+		appGuid := f.AppGuid
+		if appGuid == "" {
+			appGuid = NewGuid()
+		}
+		subject.OrganizationalUnit = []string{"app:" + appGuid}
+	}
+
+	notBefore, notAfter := f.NotBefore, f.NotAfter
+	if notBefore.IsZero() {
+		notBefore = time.Now().Add(-time.Minute)
+	}
+	if notAfter.IsZero() {
+		notAfter = time.Now().Add(defaultLifetime)
+	}
+
+	extKeyUsage := f.ExtKeyUsage
+	if extKeyUsage == nil {
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if f.KeyType == RSA2048 {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	return &x509.Certificate{
+		SerialNumber:          randomSerialNumber(),
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+	}
+}
+
+// NewGuid returns a random version 4 UUID.
+func NewGuid() string {
+	guid := make([]byte, 16)
+	if _, err := rand.Read(guid); err != nil {
+		panic(err)
+	}
+	guid[6] = guid[6]&0x0f | 0x40
+	guid[8] = guid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", guid[0:4], guid[4:6], guid[6:8], guid[8:10], guid[10:])
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	if keyType == ECDSAP256 {
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+func randomSerialNumber() *big.Int {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return serialNumber
+}
@@ -0,0 +1,56 @@
+// generate_client_certs writes the client certificate fixtures to a directory,
+// for tools outside the Ginkgo suites that need to authenticate with mutual TLS.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+)
+
+func main() {
+	outputPath := flag.String("outputPath", "certs", "directory to write the certificates to")
+	caCert := flag.String("caCert", "", "PEM encoded client CA certificate")
+	caKey := flag.String("caKey", "", "PEM encoded client CA private key")
+	flag.Parse()
+
+	if *caCert == "" || *caKey == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := generate(*outputPath, *caCert, *caKey); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(outputPath, caCert, caKey string) error {
+	authority, err := client_certs.LoadAuthority(caCert, caKey)
+	if err != nil {
+		return err
+	}
+	unknownCA, err := client_certs.UnknownCA()
+	if err != nil {
+		return err
+	}
+
+	fixtures := map[string]client_certs.Fixture{
+		"client":              client_certs.Valid(),
+		"expired":             client_certs.Expired(),
+		"not_yet_valid":       client_certs.NotYetValid(),
+		"selfsigned":          client_certs.SelfSigned(),
+		"unknown":             unknownCA,
+		"wrong_ext_key_usage": client_certs.WrongExtKeyUsage(),
+		"missing_app_guid":    client_certs.MissingAppGuid(),
+		"wrong_key_type":      client_certs.WrongKeyType(),
+	}
+	for name, fixture := range fixtures {
+		if _, _, err := authority.Write(outputPath, name, fixture); err != nil {
+			return err
+		}
+	}
+	return nil
+}
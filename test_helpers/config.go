@@ -0,0 +1,295 @@
+package test_helpers
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type BoshConfig struct {
+	Host              string `json:"host" yaml:"host"`
+	SshUsername       string `json:"bosh_ssh_username" yaml:"bosh_ssh_username"`
+	SshPrivateKeyPath string `json:"bosh_ssh_private_key_path" yaml:"bosh_ssh_private_key_path"`
+}
+
+type Config struct {
+	Bosh           *BoshConfig `json:"bosh" yaml:"bosh"`
+	ApiUrl         string      `json:"api_url" yaml:"api_url"`
+	ApiUsername    string      `json:"api_username" yaml:"api_username"`
+	ApiPassword    string      `json:"api_password" yaml:"api_password"`
+	CredentialRoot string      `json:"credential_root" yaml:"credential_root"`
+	UAACa          string      `json:"uaa_ca" yaml:"uaa_ca"`
+	DirectorHost   string      `json:"director_host" yaml:"director_host"`
+	Local          bool        `json:"local" yaml:"local"`
+
+	// Profile selects one of Profiles, whose fields override the ones above.
+	Profile  string             `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Profiles map[string]*Config `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// Required fields, by config key, for each suite.
+var (
+	IntegrationConfig    = []string{"api_url", "api_username", "api_password", "credential_root", "uaa_ca"}
+	SmokeConfig          = []string{"api_url", "api_username", "api_password"}
+	MtlsConfig           = []string{"api_url", "credential_root"}
+	TlsConfig            = []string{"api_url", "credential_root"}
+	BbrIntegrationConfig = []string{"api_url", "api_username", "api_password", "uaa_ca", "director_host",
+		"bosh.host", "bosh.bosh_ssh_username", "bosh.bosh_ssh_private_key_path"}
+)
+
+// configSetting is a Config field that can be overridden on its own by an
+// environment variable or a command-line flag.
+type configSetting struct {
+	key   string
+	env   string
+	flag  string
+	field func(cfg *Config) interface{}
+}
+
+var configSettings = []configSetting{
+	{"api_url", "CREDHUB_API_URL", "api-url", func(cfg *Config) interface{} { return &cfg.ApiUrl }},
+	{"api_username", "CREDHUB_API_USERNAME", "api-username", func(cfg *Config) interface{} { return &cfg.ApiUsername }},
+	{"api_password", "CREDHUB_API_PASSWORD", "api-password", func(cfg *Config) interface{} { return &cfg.ApiPassword }},
+	{"credential_root", "CREDHUB_CREDENTIAL_ROOT", "credential-root", func(cfg *Config) interface{} { return &cfg.CredentialRoot }},
+	{"uaa_ca", "CREDHUB_UAA_CA", "uaa-ca", func(cfg *Config) interface{} { return &cfg.UAACa }},
+	{"director_host", "CREDHUB_DIRECTOR_HOST", "director-host", func(cfg *Config) interface{} { return &cfg.DirectorHost }},
+	{"local", "CREDHUB_LOCAL", "local", func(cfg *Config) interface{} { return &cfg.Local }},
+	{"bosh.host", "CREDHUB_BOSH_HOST", "bosh-host", func(cfg *Config) interface{} { return &bosh(cfg).Host }},
+	{"bosh.bosh_ssh_username", "CREDHUB_BOSH_SSH_USERNAME", "bosh-ssh-username", func(cfg *Config) interface{} { return &bosh(cfg).SshUsername }},
+	{"bosh.bosh_ssh_private_key_path", "CREDHUB_BOSH_SSH_PRIVATE_KEY_PATH", "bosh-ssh-private-key-path", func(cfg *Config) interface{} { return &bosh(cfg).SshPrivateKeyPath }},
+}
+
+const (
+	// ConfigFileEnv names config files to load after the defaults in $PWD,
+	// separated like $PATH.
+	ConfigFileEnv = "CREDHUB_CONFIG"
+
+	// ProfileEnv selects a profile, overriding "profile" in the config files.
+	ProfileEnv = "CREDHUB_PROFILE"
+)
+
+var (
+	configFileFlag string
+	profileFlag    string
+	settingFlags   = map[string]*string{}
+)
+
+func init() {
+	flag.StringVar(&configFileFlag, "config", "", "config files to load after $"+ConfigFileEnv+", separated like $PATH")
+	flag.StringVar(&profileFlag, "profile", "", "config profile to run against, overriding $"+ProfileEnv)
+	for _, setting := range configSettings {
+		settingFlags[setting.flag] = flag.String(setting.flag, "", "overrides "+setting.key+" in the config")
+	}
+}
+
+// LoadConfig layers, from lowest to highest precedence:
+//
+//   - test_config.json, test_config.yml and test_config.yaml in $PWD, if present
+//   - the JSON or YAML files named by $CREDHUB_CONFIG
+//   - the files named by the -config flag
+//   - the selected profile from those files, chosen by -profile, $CREDHUB_PROFILE
+//     or the "profile" key, in that order
+//   - CREDHUB_* environment variables, e.g. CREDHUB_API_URL
+//   - flags, e.g. -api-url, passed to ginkgo after --
+//
+// and returns an error naming any of the required keys that are still empty.
+func LoadConfig(required ...string) (Config, error) {
+	configuration := Config{}
+
+	for _, name := range []string{"test_config.json", "test_config.yml", "test_config.yaml"} {
+		err := loadConfigFile(&configuration, path.Join(os.Getenv("PWD"), name))
+		if err != nil && !os.IsNotExist(err) {
+			return configuration, err
+		}
+	}
+
+	for _, files := range []string{os.Getenv(ConfigFileEnv), configFileFlag} {
+		for _, file := range filepath.SplitList(files) {
+			if err := loadConfigFile(&configuration, file); err != nil {
+				return configuration, err
+			}
+		}
+	}
+
+	if err := configuration.applyProfile(); err != nil {
+		return configuration, err
+	}
+
+	for _, setting := range configSettings {
+		if value, ok := os.LookupEnv(setting.env); ok {
+			if err := setting.set(&configuration, value); err != nil {
+				return configuration, fmt.Errorf("%s: %s", setting.env, err)
+			}
+		}
+	}
+
+	visited := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { visited[f.Name] = true })
+	for _, setting := range configSettings {
+		if visited[setting.flag] {
+			if err := setting.set(&configuration, *settingFlags[setting.flag]); err != nil {
+				return configuration, fmt.Errorf("-%s: %s", setting.flag, err)
+			}
+		}
+	}
+
+	configuration = applyLocalTarget(configuration)
+
+	return configuration, configuration.Validate(required...)
+}
+
+// Validate returns an error listing the required keys that are empty, with
+// the variables and flags that set them.
+func (cfg Config) Validate(required ...string) error {
+	missing := []string{}
+	for _, key := range required {
+		setting, ok := findConfigSetting(key)
+		if !ok {
+			return fmt.Errorf("unknown config key %q", key)
+		}
+		if setting.isEmpty(cfg) {
+			missing = append(missing, fmt.Sprintf("  %s (%s or -%s)", setting.key, setting.env, setting.flag))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing fields required by this suite:\n%s", cfg.Description(), strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// Description names the target for suite output, e.g. `profile "staging"`.
+func (cfg Config) Description() string {
+	switch {
+	case cfg.Local:
+		return "local fake server"
+	case cfg.Profile != "":
+		return fmt.Sprintf("profile %q", cfg.Profile)
+	default:
+		return "config"
+	}
+}
+
+// SuiteDescription adds the target the suite runs against to its name, so it
+// shows in every report.
+func SuiteDescription(suite string) string {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return suite
+	}
+	if cfg.ApiUrl == "" {
+		return fmt.Sprintf("%s (%s)", suite, cfg.Description())
+	}
+	return fmt.Sprintf("%s (%s, %s)", suite, cfg.Description(), cfg.ApiUrl)
+}
+
+func (cfg *Config) applyProfile() error {
+	if env := os.Getenv(ProfileEnv); env != "" {
+		cfg.Profile = env
+	}
+	if profileFlag != "" {
+		cfg.Profile = profileFlag
+	}
+	if cfg.Profile == "" {
+		return nil
+	}
+
+	profile, ok := cfg.Profiles[cfg.Profile]
+	if !ok || profile == nil {
+		known := []string{}
+		for name := range cfg.Profiles {
+			known = append(known, name)
+		}
+		sort.Strings(known)
+		return fmt.Errorf("unknown config profile %q, expected one of %v", cfg.Profile, known)
+	}
+
+	for _, setting := range configSettings {
+		setting.overlay(cfg, *profile)
+	}
+	return nil
+}
+
+func loadConfigFile(cfg *Config, file string) error {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(file) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(contents, cfg)
+	default:
+		err = json.Unmarshal(contents, cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	return nil
+}
+
+func findConfigSetting(key string) (configSetting, bool) {
+	for _, setting := range configSettings {
+		if setting.key == key {
+			return setting, true
+		}
+	}
+	return configSetting{}, false
+}
+
+func (s configSetting) set(cfg *Config, value string) error {
+	switch field := s.field(cfg).(type) {
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *string:
+		*field = value
+	}
+	return nil
+}
+
+func (s configSetting) overlay(cfg *Config, profile Config) {
+	if s.isEmpty(profile) {
+		return
+	}
+	switch field := s.field(cfg).(type) {
+	case *bool:
+		*field = *s.field(&profile).(*bool)
+	case *string:
+		*field = *s.field(&profile).(*string)
+	}
+}
+
+func (s configSetting) isEmpty(cfg Config) bool {
+	if cfg.Bosh != nil {
+		boshCopy := *cfg.Bosh
+		cfg.Bosh = &boshCopy
+	}
+
+	switch field := s.field(&cfg).(type) {
+	case *bool:
+		return !*field
+	case *string:
+		return *field == ""
+	}
+	return true
+}
+
+func bosh(cfg *Config) *BoshConfig {
+	if cfg.Bosh == nil {
+		cfg.Bosh = &BoshConfig{}
+	}
+	return cfg.Bosh
+}
@@ -0,0 +1,141 @@
+package test_helpers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/gomega"
+)
+
+type CredentialMetadata struct {
+	Id               string `json:"id"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	VersionCreatedAt string `json:"version_created_at"`
+}
+
+// Credential is a credential of any type, as returned by the API. Its value
+// is decoded with one of the As* methods.
+type Credential struct {
+	CredentialMetadata
+	Value json.RawMessage `json:"value"`
+}
+
+type FoundCredential struct {
+	Name             string `json:"name"`
+	VersionCreatedAt string `json:"version_created_at"`
+}
+
+// FoundNames returns the names of credentials returned by a find.
+func FoundNames(found []FoundCredential) []string {
+	names := []string{}
+	for _, credential := range found {
+		names = append(names, credential.Name)
+	}
+	return names
+}
+
+type ValueCredential struct {
+	CredentialMetadata
+	Value string `json:"value"`
+}
+
+type PasswordCredential struct {
+	CredentialMetadata
+	Value string `json:"value"`
+}
+
+type JSONCredential struct {
+	CredentialMetadata
+	Value map[string]interface{} `json:"value"`
+}
+
+type UserValue struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+type UserCredential struct {
+	CredentialMetadata
+	Value UserValue `json:"value"`
+}
+
+type SSHValue struct {
+	PublicKey            string `json:"public_key"`
+	PrivateKey           string `json:"private_key"`
+	PublicKeyFingerprint string `json:"public_key_fingerprint,omitempty"`
+}
+
+type SSHCredential struct {
+	CredentialMetadata
+	Value SSHValue `json:"value"`
+}
+
+type RSAValue struct {
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+type RSACredential struct {
+	CredentialMetadata
+	Value RSAValue `json:"value"`
+}
+
+type CertificateValue struct {
+	Ca          string `json:"ca,omitempty"`
+	CaName      string `json:"ca_name,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+	PrivateKey  string `json:"private_key,omitempty"`
+}
+
+type CertificateCredential struct {
+	CredentialMetadata
+	Value CertificateValue `json:"value"`
+}
+
+func (c Credential) AsValue() ValueCredential {
+	typed := ValueCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("value", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsPassword() PasswordCredential {
+	typed := PasswordCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("password", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsJSON() JSONCredential {
+	typed := JSONCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("json", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsUser() UserCredential {
+	typed := UserCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("user", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsSSH() SSHCredential {
+	typed := SSHCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("ssh", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsRSA() RSACredential {
+	typed := RSACredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("rsa", &typed.Value)
+	return typed
+}
+
+func (c Credential) AsCertificate() CertificateCredential {
+	typed := CertificateCredential{CredentialMetadata: c.CredentialMetadata}
+	c.decodeValue("certificate", &typed.Value)
+	return typed
+}
+
+func (c Credential) decodeValue(credentialType string, value interface{}) {
+	ExpectWithOffset(2, c.Type).To(Equal(credentialType), "credential %s has the wrong type", c.Name)
+	ExpectWithOffset(2, json.Unmarshal(c.Value, value)).To(Succeed())
+}
@@ -0,0 +1,352 @@
+package test_helpers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+)
+
+// ApiError is an error response from CredHub or UAA.
+type ApiError struct {
+	StatusCode  int
+	Message     string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *ApiError) Error() string {
+	if e.Description != "" {
+		return e.Description
+	}
+	return e.Message
+}
+
+// IsNotFound reports whether err is CredHub saying a credential does not exist.
+func IsNotFound(err error) bool {
+	apiError, ok := err.(*ApiError)
+	return ok && apiError.StatusCode == http.StatusNotFound
+}
+
+// CredhubClient talks to the CredHub API directly, authenticating with either
+// a UAA bearer token or a client certificate.
+type CredhubClient struct {
+	ApiUrl     string
+	httpClient *http.Client
+	token      string
+}
+
+// NewTokenClient logs in to the UAA advertised by the server with the
+// configured username and password, trusting server_ca_cert.pem from the
+// credential root and the UAA CA.
+func NewTokenClient(cfg Config) (*CredhubClient, error) {
+	trustedCAs, err := loadTrustedCAs(path.Join(cfg.CredentialRoot, "server_ca_cert.pem"), cfg.UAACa)
+	if err != nil {
+		return nil, err
+	}
+	return newTokenClient(cfg, &tls.Config{RootCAs: trustedCAs})
+}
+
+// NewTokenClientSkipTls is NewTokenClient without server certificate validation.
+func NewTokenClientSkipTls(cfg Config) (*CredhubClient, error) {
+	return newTokenClient(cfg, &tls.Config{InsecureSkipVerify: true})
+}
+
+func newTokenClient(cfg Config, tlsConfig *tls.Config) (*CredhubClient, error) {
+	client := &CredhubClient{
+		ApiUrl:     cfg.ApiUrl,
+		httpClient: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}
+
+	info := struct {
+		AuthServer struct {
+			Url string `json:"url"`
+		} `json:"auth-server"`
+	}{}
+	if err := client.do("GET", "/info", nil, &info); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"password"},
+		"response_type": {"token"},
+		"username":      {cfg.ApiUsername},
+		"password":      {cfg.ApiPassword},
+	}
+	request, err := http.NewRequest("POST", info.AuthServer.Url+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth("credhub_cli", "")
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := client.send(request, &token); err != nil {
+		return nil, err
+	}
+	client.token = token.AccessToken
+
+	return client, nil
+}
+
+// NewMtlsClient authenticates with the given client certificate and key,
+// trusting server_ca_cert.pem from the credential root.
+func NewMtlsClient(cfg Config, clientCertPath, clientKeyPath string) (*CredhubClient, error) {
+	trustedCAs, err := loadTrustedCAs(path.Join(cfg.CredentialRoot, "server_ca_cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	clientCertificate, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{clientCertificate},
+		RootCAs:      trustedCAs,
+	}
+	return &CredhubClient{
+		ApiUrl:     cfg.ApiUrl,
+		httpClient: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}, nil
+}
+
+// NewFixtureClient authenticates with a certificate for the fixture, issued
+// by the client CA from the credential root.
+func NewFixtureClient(cfg Config, fixture client_certs.Fixture) (*CredhubClient, error) {
+	authority, err := client_certs.LoadAuthority(
+		path.Join(cfg.CredentialRoot, "client_ca_cert.pem"),
+		path.Join(cfg.CredentialRoot, "client_ca_private.pem"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	certsDir, err := ioutil.TempDir("", "mtls-client-certs")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(certsDir)
+
+	clientCertPath, clientKeyPath, err := authority.Write(certsDir, "client", fixture)
+	if err != nil {
+		return nil, err
+	}
+	return NewMtlsClient(cfg, clientCertPath, clientKeyPath)
+}
+
+func loadTrustedCAs(caPaths ...string) (*x509.CertPool, error) {
+	trustedCAs := x509.NewCertPool()
+	for _, caPath := range caPaths {
+		if caPath == "" {
+			continue
+		}
+		ca, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return nil, err
+		}
+		if !trustedCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse CA certificate %s", caPath)
+		}
+	}
+	return trustedCAs, nil
+}
+
+// Token returns the Authorization header value the client sends, if any.
+func (c *CredhubClient) Token() string {
+	if c.token == "" {
+		return ""
+	}
+	return "bearer " + c.token
+}
+
+func (c *CredhubClient) GetByName(name string) (Credential, error) {
+	response := struct {
+		Data []Credential `json:"data"`
+	}{}
+	err := c.do("GET", "/api/v1/data?current=true&name="+url.QueryEscape(name), nil, &response)
+	if err != nil {
+		return Credential{}, err
+	}
+	if len(response.Data) == 0 {
+		return Credential{}, &ApiError{StatusCode: http.StatusNotFound, Message: credentialNotFoundError}
+	}
+	return response.Data[0], nil
+}
+
+// GetAllVersions returns every version of the named credential, newest first.
+func (c *CredhubClient) GetAllVersions(name string) ([]Credential, error) {
+	response := struct {
+		Data []Credential `json:"data"`
+	}{}
+	err := c.do("GET", "/api/v1/data?name="+url.QueryEscape(name), nil, &response)
+	return response.Data, err
+}
+
+func (c *CredhubClient) GetById(id string) (Credential, error) {
+	credential := Credential{}
+	err := c.do("GET", "/api/v1/data/"+url.PathEscape(id), nil, &credential)
+	return credential, err
+}
+
+func (c *CredhubClient) Set(name, credentialType string, value interface{}, overwrite bool) (Credential, error) {
+	credential := Credential{}
+	err := c.do("PUT", "/api/v1/data", map[string]interface{}{
+		"name":      name,
+		"type":      credentialType,
+		"value":     value,
+		"overwrite": overwrite,
+	}, &credential)
+	c.track(name, err)
+	return credential, err
+}
+
+func (c *CredhubClient) Generate(name, credentialType string, parameters map[string]interface{}, overwrite bool) (Credential, error) {
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+	credential := Credential{}
+	err := c.do("POST", "/api/v1/data", map[string]interface{}{
+		"name":       name,
+		"type":       credentialType,
+		"parameters": parameters,
+		"overwrite":  overwrite,
+	}, &credential)
+	c.track(name, err)
+	return credential, err
+}
+
+func (c *CredhubClient) Regenerate(name string) (Credential, error) {
+	credential := Credential{}
+	err := c.do("POST", "/api/v1/data", map[string]interface{}{
+		"name":       name,
+		"regenerate": true,
+	}, &credential)
+	return credential, err
+}
+
+func (c *CredhubClient) Delete(name string) error {
+	err := c.do("DELETE", "/api/v1/data?name="+url.QueryEscape(name), nil, nil)
+	if err == nil {
+		UntrackCredential(name)
+	}
+	return err
+}
+
+func (c *CredhubClient) FindByPath(path string) ([]FoundCredential, error) {
+	return c.find("path=" + url.QueryEscape(path))
+}
+
+func (c *CredhubClient) FindByNameLike(nameLike string) ([]FoundCredential, error) {
+	return c.find("name-like=" + url.QueryEscape(nameLike))
+}
+
+func (c *CredhubClient) find(query string) ([]FoundCredential, error) {
+	response := struct {
+		Credentials []FoundCredential `json:"credentials"`
+	}{}
+	err := c.do("GET", "/api/v1/data?"+query, nil, &response)
+	return response.Credentials, err
+}
+
+// Interpolate replaces the credhub-ref entries in a VCAP_SERVICES document.
+func (c *CredhubClient) Interpolate(services interface{}) (map[string]interface{}, error) {
+	interpolated := map[string]interface{}{}
+	err := c.do("POST", "/api/v1/interpolate", services, &interpolated)
+	return interpolated, err
+}
+
+// Send makes a request with the client's authentication and returns the
+// response as is, whatever its status.
+func (c *CredhubClient) Send(method, apiPath string, body []byte) (int, []byte, error) {
+	request, err := http.NewRequest(method, c.ApiUrl+apiPath, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		request.Header.Set("Authorization", c.Token())
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	return response.StatusCode, responseBody, err
+}
+
+// track registers a credential the client created for CleanupCredentials.
+func (c *CredhubClient) track(name string, err error) {
+	if err != nil {
+		return
+	}
+	TrackCredential(name, func(name string) error {
+		if err := c.Delete(name); err != nil && !IsNotFound(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (c *CredhubClient) do(method, apiPath string, body interface{}, result interface{}) error {
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequest(method, c.ApiUrl+apiPath, requestBody)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		request.Header.Set("Authorization", c.Token())
+	}
+
+	return c.send(request, result)
+}
+
+func (c *CredhubClient) send(request *http.Request, result interface{}) error {
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= 300 {
+		apiError := &ApiError{StatusCode: response.StatusCode}
+		if json.Unmarshal(body, apiError) != nil || (apiError.Message == "" && apiError.Description == "") {
+			apiError.Message = strings.TrimSpace(string(body))
+		}
+		return apiError
+	}
+
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
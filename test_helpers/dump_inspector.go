@@ -0,0 +1,160 @@
+package test_helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// FakeCredentialValue is set by specs so that CI can check that it never
+// leaks, e.g. into logs or backups.
+const FakeCredentialValue = "FAKE-CREDENTIAL-VALUE"
+
+// minimumSecretLength keeps short values, which could appear anywhere in a
+// dump by chance, out of Secrets.
+const minimumSecretLength = 8
+
+// Secrets returns the values CredHub encrypts at rest from every version in
+// the snapshot: values, passwords, the strings in JSON credentials and private
+// keys. Usernames, public keys and certificates are stored in plaintext. It
+// returns an error naming any version with no secret long enough to search a
+// dump for, so that no credential goes unchecked.
+func (s CredentialSnapshot) Secrets() ([]string, error) {
+	secrets := []string{}
+	unchecked := []string{}
+	for _, name := range sortedKeys(s.Versions) {
+		for _, version := range s.Versions[name] {
+			added := 0
+			add := func(secret string) {
+				if len(secret) >= minimumSecretLength {
+					secrets = append(secrets, secret)
+					added++
+				}
+			}
+
+			switch version.Type {
+			case "value":
+				add(version.AsValue().Value)
+			case "password":
+				add(version.AsPassword().Value)
+			case "json":
+				for _, leaf := range jsonStrings(version.AsJSON().Value) {
+					add(leaf)
+				}
+			case "user":
+				add(version.AsUser().Value.Password)
+			case "ssh":
+				add(version.AsSSH().Value.PrivateKey)
+			case "rsa":
+				add(version.AsRSA().Value.PrivateKey)
+			case "certificate":
+				add(version.AsCertificate().Value.PrivateKey)
+			}
+
+			if added == 0 {
+				unchecked = append(unchecked, fmt.Sprintf("%s (version %s)", name, version.Id))
+			}
+		}
+	}
+
+	if len(unchecked) > 0 {
+		return secrets, fmt.Errorf("no secret of at least %d characters to search for in %s",
+			minimumSecretLength, strings.Join(unchecked, ", "))
+	}
+	return secrets, nil
+}
+
+func jsonStrings(value interface{}) []string {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}
+	case map[string]interface{}:
+		leaves := []string{}
+		for _, nested := range typed {
+			leaves = append(leaves, jsonStrings(nested)...)
+		}
+		return leaves
+	case []interface{}:
+		leaves := []string{}
+		for _, nested := range typed {
+			leaves = append(leaves, jsonStrings(nested)...)
+		}
+		return leaves
+	}
+	return nil
+}
+
+// FindPlaintext returns the secrets that appear unencrypted in a database
+// dump. It also searches gzipped dumps and the zlib-compressed table data in
+// pg_dump's custom format.
+func FindPlaintext(dumpPath string, secrets []string) ([]string, error) {
+	dump, err := ioutil.ReadFile(dumpPath)
+	if err != nil {
+		return nil, err
+	}
+	contents := decompressedContents(dump)
+
+	found := []string{}
+	for _, secret := range secrets {
+	search:
+		for _, needle := range plaintextForms(secret) {
+			for _, content := range contents {
+				if bytes.Contains(content, []byte(needle)) {
+					found = append(found, secret)
+					break search
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// plaintextForms are the ways a secret may be written to a dump: as is,
+// escaped in JSON or a COPY statement, or, for PEM keys, line by line.
+func plaintextForms(secret string) []string {
+	forms := []string{secret}
+	if escaped, err := json.Marshal(secret); err == nil {
+		forms = append(forms, strings.Trim(string(escaped), `"`))
+	}
+
+	longestLine := ""
+	for _, line := range strings.Split(secret, "\n") {
+		if len(line) > len(longestLine) && !strings.HasPrefix(line, "-----") {
+			longestLine = line
+		}
+	}
+	if longestLine != secret && len(longestLine) >= minimumSecretLength {
+		forms = append(forms, longestLine)
+	}
+	return forms
+}
+
+func decompressedContents(dump []byte) [][]byte {
+	contents := [][]byte{dump}
+
+	if reader, err := gzip.NewReader(bytes.NewReader(dump)); err == nil {
+		if decompressed, _ := ioutil.ReadAll(reader); len(decompressed) > 0 {
+			contents = append(contents, decompressed)
+		}
+	}
+
+	// A zlib stream starts with 0x78 and a flag byte that makes the first two
+	// bytes a multiple of 31.
+	for i := 0; i+1 < len(dump); i++ {
+		if dump[i] != 0x78 || (uint16(dump[i])<<8|uint16(dump[i+1]))%31 != 0 {
+			continue
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(dump[i:]))
+		if err != nil {
+			continue
+		}
+		if decompressed, _ := ioutil.ReadAll(reader); len(decompressed) > 0 {
+			contents = append(contents, decompressed)
+		}
+	}
+	return contents
+}
@@ -0,0 +1,135 @@
+package test_helpers_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the dump inspector", func() {
+	var (
+		privateKey string
+		snapshot   CredentialSnapshot
+	)
+
+	BeforeEach(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		privateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+		snapshot = CredentialSnapshot{Versions: map[string][]Credential{
+			"/value":       {credential("/value", "value", "value-secret-2"), credential("/value", "value", "value-secret-1")},
+			"/password":    {credential("/password", "password", `pass"word\secret`)},
+			"/json":        {credential("/json", "json", map[string]interface{}{"version": 1, "nested": []string{"json-secret-1"}})},
+			"/user":        {credential("/user", "user", map[string]string{"username": "some-username", "password": "user-password"})},
+			"/certificate": {credential("/certificate", "certificate", map[string]string{"certificate": "not-a-secret", "private_key": privateKey})},
+		}}
+	})
+
+	Describe("collecting secrets", func() {
+		It("returns the encrypted values of every version", func() {
+			secrets, err := snapshot.Secrets()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secrets).To(ConsistOf("value-secret-2", "value-secret-1", `pass"word\secret`, "json-secret-1", "user-password", privateKey))
+		})
+
+		It("fails when a version has no secret long enough to search for", func() {
+			snapshot.Versions["/short"] = []Credential{credential("/short", "value", "short")}
+
+			_, err := snapshot.Secrets()
+			Expect(err).To(MatchError(ContainSubstring("/short (version /short-id)")))
+		})
+	})
+
+	Describe("searching a dump", func() {
+		var (
+			dir     string
+			secrets []string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "dump-inspector")
+			Expect(err).NotTo(HaveOccurred())
+
+			secrets, err = snapshot.Secrets()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		write := func(file string, contents []byte) string {
+			dump := filepath.Join(dir, file)
+			Expect(ioutil.WriteFile(dump, contents, 0600)).To(Succeed())
+			return dump
+		}
+
+		It("finds every secret in a dump that does not encrypt them", func() {
+			plaintext, err := json.Marshal(snapshot.Versions)
+			Expect(err).NotTo(HaveOccurred())
+
+			var gzipped bytes.Buffer
+			gzipWriter := gzip.NewWriter(&gzipped)
+			gzipWriter.Write(plaintext)
+			Expect(gzipWriter.Close()).To(Succeed())
+
+			// pg_dump's custom format compresses each table's data with zlib
+			// between uncompressed headers.
+			var custom bytes.Buffer
+			custom.WriteString("PGDMP header")
+			zlibWriter := zlib.NewWriter(&custom)
+			zlibWriter.Write(plaintext)
+			Expect(zlibWriter.Close()).To(Succeed())
+			custom.WriteString("trailer")
+
+			for file, contents := range map[string][]byte{
+				"plaintext.json":    plaintext,
+				"plaintext.json.gz": gzipped.Bytes(),
+				"custom.dump":       custom.Bytes(),
+			} {
+				leaked, err := FindPlaintext(write(file, contents), secrets)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(leaked).To(Equal(secrets), "%s contains every secret unencrypted", file)
+			}
+		})
+
+		It("finds a private key written with other line endings", func() {
+			dump := write("dump.sql", bytes.Replace([]byte(privateKey), []byte("\n"), []byte("\r\n"), -1))
+
+			leaked, err := FindPlaintext(dump, secrets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leaked).To(Equal([]string{privateKey}))
+		})
+
+		It("finds nothing in a dump without the secrets", func() {
+			dump := write("encrypted.json", []byte(`{"encrypted_value": "c29tZSBjaXBoZXJ0ZXh0"}`))
+
+			leaked, err := FindPlaintext(dump, secrets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leaked).To(BeEmpty())
+		})
+	})
+})
+
+func credential(name, credentialType string, value interface{}) Credential {
+	encoded, err := json.Marshal(value)
+	Expect(err).NotTo(HaveOccurred())
+	return Credential{
+		CredentialMetadata: CredentialMetadata{Id: name + "-id", Name: name, Type: credentialType},
+		Value:              encoded,
+	}
+}
@@ -0,0 +1,490 @@
+package test_helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	lowerCharacters   = "abcdefghijklmnopqrstuvwxyz"
+	upperCharacters   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numberCharacters  = "0123456789"
+	specialCharacters = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+var keyUsages = map[string]x509.KeyUsage{
+	"digital_signature": x509.KeyUsageDigitalSignature,
+	"non_repudiation":   x509.KeyUsageContentCommitment,
+	"key_encipherment":  x509.KeyUsageKeyEncipherment,
+	"data_encipherment": x509.KeyUsageDataEncipherment,
+	"key_agreement":     x509.KeyUsageKeyAgreement,
+	"key_cert_sign":     x509.KeyUsageCertSign,
+	"crl_sign":          x509.KeyUsageCRLSign,
+	"encipher_only":     x509.KeyUsageEncipherOnly,
+	"decipher_only":     x509.KeyUsageDecipherOnly,
+}
+
+var extendedKeyUsages = map[string]x509.ExtKeyUsage{
+	"client_auth":      x509.ExtKeyUsageClientAuth,
+	"server_auth":      x509.ExtKeyUsageServerAuth,
+	"code_signing":     x509.ExtKeyUsageCodeSigning,
+	"email_protection": x509.ExtKeyUsageEmailProtection,
+	"timestamping":     x509.ExtKeyUsageTimeStamping,
+}
+
+type generationParameters struct {
+	Length         int  `json:"length,omitempty"`
+	ExcludeUpper   bool `json:"exclude_upper,omitempty"`
+	ExcludeLower   bool `json:"exclude_lower,omitempty"`
+	ExcludeNumber  bool `json:"exclude_number,omitempty"`
+	IncludeSpecial bool `json:"include_special,omitempty"`
+
+	Username string `json:"username,omitempty"`
+
+	KeyLength  int    `json:"key_length,omitempty"`
+	SshComment string `json:"ssh_comment,omitempty"`
+
+	CommonName       string   `json:"common_name,omitempty"`
+	Organization     string   `json:"organization,omitempty"`
+	OrganizationUnit string   `json:"organization_unit,omitempty"`
+	Locality         string   `json:"locality,omitempty"`
+	State            string   `json:"state,omitempty"`
+	Country          string   `json:"country,omitempty"`
+	AlternativeNames []string `json:"alternative_names,omitempty"`
+	KeyUsage         []string `json:"key_usage,omitempty"`
+	ExtendedKeyUsage []string `json:"extended_key_usage,omitempty"`
+	Duration         int      `json:"duration,omitempty"`
+	Ca               string   `json:"ca,omitempty"`
+	IsCa             bool     `json:"is_ca,omitempty"`
+	SelfSign         bool     `json:"self_sign,omitempty"`
+}
+
+func generatePassword(params generationParameters) (string, error) {
+	length := params.Length
+	if length == 0 {
+		length = 30
+	}
+	if length < 4 || length > 200 {
+		return "", errors.New("The password length must be between 4 and 200 characters. Please validate your input and retry your request.")
+	}
+
+	var classes []string
+	if !params.ExcludeLower {
+		classes = append(classes, lowerCharacters)
+	}
+	if !params.ExcludeUpper {
+		classes = append(classes, upperCharacters)
+	}
+	if !params.ExcludeNumber {
+		classes = append(classes, numberCharacters)
+	}
+	if params.IncludeSpecial {
+		classes = append(classes, specialCharacters)
+	}
+	if len(classes) == 0 {
+		return "", errors.New("The combination of parameters in the request is not allowed. Please validate your input and retry your request.")
+	}
+
+	// Like CredHub, every enabled character class appears at least once.
+	password := make([]byte, 0, length)
+	for _, class := range classes {
+		password = append(password, class[randomInt(len(class))])
+	}
+	all := strings.Join(classes, "")
+	for len(password) < length {
+		password = append(password, all[randomInt(len(all))])
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j := randomInt(i + 1)
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func generateUsername() string {
+	username := make([]byte, 20)
+	for i := range username {
+		username[i] = lowerCharacters[randomInt(len(lowerCharacters))]
+	}
+	return string(username)
+}
+
+func randomInt(max int) int {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		panic(err)
+	}
+	return int(n.Int64())
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func validateKeyLength(keyLength int) (int, error) {
+	switch keyLength {
+	case 0:
+		return 2048, nil
+	case 2048, 3072, 4096:
+		return keyLength, nil
+	}
+	return 0, errors.New("The provided key length is not supported. Valid values include '2048', '3072' and '4096'.")
+}
+
+func generateRSAKey(keyLength int) (*rsa.PrivateKey, error) {
+	keyLength, err := validateKeyLength(keyLength)
+	if err != nil {
+		return nil, err
+	}
+	return rsa.GenerateKey(rand.Reader, keyLength)
+}
+
+func privateKeyPem(key *rsa.PrivateKey) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func publicKeyPem(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func generateRSA(params generationParameters) (fakeKeyValue, error) {
+	key, err := generateRSAKey(params.KeyLength)
+	if err != nil {
+		return fakeKeyValue{}, err
+	}
+	publicKey, err := publicKeyPem(key)
+	if err != nil {
+		return fakeKeyValue{}, err
+	}
+	return fakeKeyValue{PublicKey: publicKey, PrivateKey: privateKeyPem(key)}, nil
+}
+
+func generateSSH(params generationParameters) (fakeKeyValue, error) {
+	key, err := generateRSAKey(params.KeyLength)
+	if err != nil {
+		return fakeKeyValue{}, err
+	}
+
+	wire := &bytes.Buffer{}
+	writeSSHField(wire, []byte("ssh-rsa"))
+	writeSSHField(wire, sshMpint(big.NewInt(int64(key.E))))
+	writeSSHField(wire, sshMpint(key.N))
+
+	publicKey := "ssh-rsa " + base64.StdEncoding.EncodeToString(wire.Bytes())
+	if params.SshComment != "" {
+		publicKey += " " + params.SshComment
+	}
+	fingerprint := sha256.Sum256(wire.Bytes())
+
+	return fakeKeyValue{
+		PublicKey:            publicKey,
+		PrivateKey:           privateKeyPem(key),
+		PublicKeyFingerprint: base64.RawStdEncoding.EncodeToString(fingerprint[:]),
+	}, nil
+}
+
+func writeSSHField(wire *bytes.Buffer, field []byte) {
+	binary.Write(wire, binary.BigEndian, uint32(len(field)))
+	wire.Write(field)
+}
+
+func sshMpint(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+// signingAuthority is the certificate and key a generated certificate is signed with.
+type signingAuthority struct {
+	certificate *x509.Certificate
+	pem         string
+	key         *rsa.PrivateKey
+}
+
+func generateCertificate(params generationParameters, ca *signingAuthority) (fakeCertificateValue, error) {
+	if params.CommonName == "" && params.Organization == "" && params.OrganizationUnit == "" &&
+		params.Locality == "" && params.State == "" && params.Country == "" && len(params.AlternativeNames) == 0 {
+		return fakeCertificateValue{}, errors.New("You must provide a common name or an alternative name when generating a certificate. Please validate your input and retry your request.")
+	}
+
+	duration := params.Duration
+	if duration == 0 {
+		duration = 365
+	}
+	if duration < 1 || duration > 3650 {
+		return fakeCertificateValue{}, errors.New("The provided duration must be between 1 and 3650 days.")
+	}
+
+	template := &x509.Certificate{
+		Subject:               subjectFromParameters(params),
+		BasicConstraintsValid: true,
+		IsCA:                  params.IsCa,
+	}
+	template.NotBefore = time.Now().UTC().Truncate(time.Second)
+	template.NotAfter = template.NotBefore.Add(time.Duration(duration) * 24 * time.Hour)
+
+	for _, name := range params.AlternativeNames {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if strings.ContainsAny(name, " @/") || name == "" {
+			return fakeCertificateValue{}, errors.New("A provided alternative name is not valid. Please validate your input and retry your request.")
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	for _, usage := range params.KeyUsage {
+		keyUsage, ok := keyUsages[usage]
+		if !ok {
+			return fakeCertificateValue{}, fmt.Errorf("The provided key usage '%s' is not supported. Valid values include digital_signature, non_repudiation, key_encipherment, data_encipherment, key_agreement, key_cert_sign, crl_sign, encipher_only and decipher_only.", usage)
+		}
+		template.KeyUsage |= keyUsage
+	}
+	if params.IsCa {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	for _, usage := range params.ExtendedKeyUsage {
+		extKeyUsage, ok := extendedKeyUsages[usage]
+		if !ok {
+			return fakeCertificateValue{}, fmt.Errorf("The provided extended key usage '%s' is not supported. Valid values include client_auth, server_auth, code_signing, email_protection and timestamping.", usage)
+		}
+		template.ExtKeyUsage = append(template.ExtKeyUsage, extKeyUsage)
+	}
+
+	key, err := generateRSAKey(params.KeyLength)
+	if err != nil {
+		return fakeCertificateValue{}, err
+	}
+
+	certificate, err := signCertificate(template, key, ca)
+	if err != nil {
+		return fakeCertificateValue{}, err
+	}
+
+	value := fakeCertificateValue{Certificate: certificate, PrivateKey: privateKeyPem(key)}
+	if ca != nil {
+		value.Ca = ca.pem
+	} else if params.IsCa {
+		value.Ca = certificate
+	}
+	return value, nil
+}
+
+// signCertificate signs template with ca, or self-signs it with key when ca is nil.
+func signCertificate(template *x509.Certificate, key *rsa.PrivateKey, ca *signingAuthority) (string, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return "", err
+	}
+	template.SerialNumber = serialNumber
+
+	subjectKeyId, err := subjectKeyIdentifier(key)
+	if err != nil {
+		return "", err
+	}
+	template.SubjectKeyId = subjectKeyId
+
+	parent, signingKey := template, key
+	if ca != nil {
+		parent, signingKey = ca.certificate, ca.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signingKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
+func subjectFromParameters(params generationParameters) pkix.Name {
+	name := pkix.Name{CommonName: params.CommonName}
+	if params.Organization != "" {
+		name.Organization = []string{params.Organization}
+	}
+	if params.OrganizationUnit != "" {
+		name.OrganizationalUnit = []string{params.OrganizationUnit}
+	}
+	if params.Locality != "" {
+		name.Locality = []string{params.Locality}
+	}
+	if params.State != "" {
+		name.Province = []string{params.State}
+	}
+	if params.Country != "" {
+		name.Country = []string{params.Country}
+	}
+	return name
+}
+
+func subjectKeyIdentifier(key *rsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(info.PublicKey.Bytes)
+	return sum[:], nil
+}
+
+func parseSigningAuthority(value fakeCertificateValue) (*signingAuthority, error) {
+	certBlock, _ := pem.Decode([]byte(value.Certificate))
+	keyBlock, _ := pem.Decode([]byte(value.PrivateKey))
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("The provided CA value is not valid. Please validate your input and retry your request.")
+	}
+	certificate, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !certificate.IsCA {
+		return nil, errors.New("The provided certificate is not a certificate authority. Please validate your input and retry your request.")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &signingAuthority{certificate: certificate, pem: value.Certificate, key: key}, nil
+}
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// sha512Crypt produces the "$6$" password hash CredHub returns for user credentials.
+func sha512Crypt(password string) string {
+	salt := make([]byte, 16)
+	for i := range salt {
+		salt[i] = cryptAlphabet[randomInt(len(cryptAlphabet))]
+	}
+	return sha512CryptWithSalt([]byte(password), salt)
+}
+
+func sha512CryptWithSalt(password, salt []byte) string {
+	const rounds = 5000
+
+	alternate := sha512.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alternateSum := alternate.Sum(nil)
+
+	digest := sha512.New()
+	digest.Write(password)
+	digest.Write(salt)
+	for i := len(password); i > 0; i -= 64 {
+		digest.Write(alternateSum[:minInt(i, 64)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			digest.Write(alternateSum)
+		} else {
+			digest.Write(password)
+		}
+	}
+	result := digest.Sum(nil)
+
+	passwordDigest := sha512.New()
+	for range password {
+		passwordDigest.Write(password)
+	}
+	passwordSequence := repeatToLength(passwordDigest.Sum(nil), len(password))
+
+	saltDigest := sha512.New()
+	for i := 0; i < 16+int(result[0]); i++ {
+		saltDigest.Write(salt)
+	}
+	saltSequence := repeatToLength(saltDigest.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		round := sha512.New()
+		if i&1 != 0 {
+			round.Write(passwordSequence)
+		} else {
+			round.Write(result)
+		}
+		if i%3 != 0 {
+			round.Write(saltSequence)
+		}
+		if i%7 != 0 {
+			round.Write(passwordSequence)
+		}
+		if i&1 != 0 {
+			round.Write(result)
+		} else {
+			round.Write(passwordSequence)
+		}
+		result = round.Sum(nil)
+	}
+
+	encoded := &strings.Builder{}
+	encode := func(b2, b1, b0 byte, n int) {
+		w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for ; n > 0; n-- {
+			encoded.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for i := 0; i < 21; i++ {
+		a, b, c := result[i], result[i+21], result[i+42]
+		switch i % 3 {
+		case 0:
+			encode(a, b, c, 4)
+		case 1:
+			encode(b, c, a, 4)
+		case 2:
+			encode(c, a, b, 4)
+		}
+	}
+	encode(0, 0, result[63], 2)
+
+	return "$6$" + string(salt) + "$" + encoded.String()
+}
+
+func repeatToLength(sum []byte, length int) []byte {
+	sequence := make([]byte, 0, length)
+	for len(sequence) < length {
+		sequence = append(sequence, sum[:minInt(length-len(sequence), len(sum))]...)
+	}
+	return sequence
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,526 @@
+package test_helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+)
+
+const unauthenticatedError = "Full authentication is required to access this resource"
+
+// FakeServer is an in-process stand-in for a CredHub server and its UAA, good
+// enough to run the acceptance suites without a deployment.
+type FakeServer struct {
+	URL            string
+	CredentialRoot string
+	Username       string
+	Password       string
+
+	server    *httptest.Server
+	store     *fakeStore
+	clientCAs *x509.CertPool
+	tokenKey  []byte
+}
+
+// NewFakeServer starts a fake CredHub over TLS and writes its CA to
+// server_ca_cert.pem under a new credential root. Client certificates are
+// trusted when they are signed by the client CA found in clientCaRoot
+// (client_ca_cert.pem and client_ca_private.pem), or by a freshly generated
+// client CA when clientCaRoot does not contain one.
+func NewFakeServer(username, password, clientCaRoot string) (*FakeServer, error) {
+	credentialRoot, err := ioutil.TempDir("", "fake-credhub")
+	if err != nil {
+		return nil, err
+	}
+
+	fake := &FakeServer{
+		CredentialRoot: credentialRoot,
+		Username:       username,
+		Password:       password,
+		store:          newFakeStore(),
+		tokenKey:       make([]byte, 32),
+	}
+	if _, err := rand.Read(fake.tokenKey); err != nil {
+		return nil, err
+	}
+
+	serverCertificate, err := fake.writeServerCertificates()
+	if err != nil {
+		return nil, err
+	}
+	clientCa, err := fake.writeClientCa(clientCaRoot)
+	if err != nil {
+		return nil, err
+	}
+	fake.clientCAs = x509.NewCertPool()
+	fake.clientCAs.AddCert(clientCa)
+
+	fake.server = httptest.NewUnstartedServer(http.HandlerFunc(fake.ServeHTTP))
+	fake.server.Listener = certificateUnknownListener{fake.server.Listener}
+	fake.server.Config.ErrorLog = log.New(GinkgoWriter, "fake credhub: ", 0)
+	fake.server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    fake.clientCAs,
+		MinVersion:   tls.VersionTLS12,
+		// Client certificate failures are only reported during the handshake up to TLS 1.2.
+		MaxVersion: tls.VersionTLS12,
+		// CredHub only accepts client certificates with RSA keys.
+		VerifyPeerCertificate: func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if len(verifiedChains) > 0 {
+				if _, ok := verifiedChains[0][0].PublicKey.(*rsa.PublicKey); !ok {
+					return errors.New("client certificate does not have an RSA key")
+				}
+			}
+			return nil
+		},
+	}
+	fake.server.StartTLS()
+	fake.URL = fake.server.URL
+
+	return fake, nil
+}
+
+func (f *FakeServer) Close() {
+	f.server.Close()
+	os.RemoveAll(f.CredentialRoot)
+}
+
+func (f *FakeServer) writeServerCertificates() (tls.Certificate, error) {
+	ca, err := generateCertificate(generationParameters{CommonName: "fake_credhub_ca", IsCa: true}, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	authority, err := parseSigningAuthority(ca)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	server, err := generateCertificate(generationParameters{
+		CommonName:       "127.0.0.1",
+		AlternativeNames: []string{"127.0.0.1", "localhost"},
+		ExtendedKeyUsage: []string{"server_auth"},
+	}, authority)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := ioutil.WriteFile(path.Join(f.CredentialRoot, "server_ca_cert.pem"), []byte(ca.Certificate), 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair([]byte(server.Certificate), []byte(server.PrivateKey))
+}
+
+func (f *FakeServer) writeClientCa(clientCaRoot string) (*x509.Certificate, error) {
+	var certificatePem, keyPem []byte
+	if clientCaRoot != "" {
+		certificatePem, _ = ioutil.ReadFile(path.Join(clientCaRoot, "client_ca_cert.pem"))
+		keyPem, _ = ioutil.ReadFile(path.Join(clientCaRoot, "client_ca_private.pem"))
+	}
+	if certificatePem == nil || keyPem == nil {
+		ca, err := generateCertificate(generationParameters{CommonName: "credhub_client_ca", IsCa: true}, nil)
+		if err != nil {
+			return nil, err
+		}
+		certificatePem, keyPem = []byte(ca.Certificate), []byte(ca.PrivateKey)
+	}
+
+	if err := ioutil.WriteFile(path.Join(f.CredentialRoot, "client_ca_cert.pem"), certificatePem, 0600); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path.Join(f.CredentialRoot, "client_ca_private.pem"), keyPem, 0600); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(certificatePem)
+	if block == nil {
+		return nil, errors.New("failed to parse client CA PEM")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/info":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"app":         map[string]string{"name": "CredHub", "version": "fake"},
+			"auth-server": map[string]string{"url": f.URL},
+		})
+	case r.URL.Path == "/version":
+		writeJSON(w, http.StatusOK, map[string]string{"version": "fake"})
+	case r.URL.Path == "/oauth/token" && r.Method == "POST":
+		f.handleToken(w, r)
+	case strings.HasPrefix(r.URL.Path, "/oauth/token/revoke/"):
+		w.WriteHeader(http.StatusOK)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/"):
+		actor, ok := f.authenticate(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{
+				"error":             "unauthorized",
+				"error_description": unauthenticatedError,
+			})
+			return
+		}
+		f.handleApi(w, r, actor)
+	case strings.HasPrefix(r.URL.Path, "/fake/"):
+		actor, ok := f.authenticate(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{
+				"error":             "unauthorized",
+				"error_description": unauthenticatedError,
+			})
+			return
+		}
+		// Backups hold every actor's credentials, so like bbr, which needs the
+		// director, only the UAA admin may take or restore one.
+		if actor != "uaa-user:"+f.Username {
+			writeError(w, fakeError{status: http.StatusForbidden, message: "You do not have sufficient authorization to back up or restore CredHub."})
+			return
+		}
+		f.handleBackup(w, r)
+	default:
+		writeError(w, fakeError{status: http.StatusNotFound, message: "The request could not be fulfilled because the resource could not be found."})
+	}
+}
+
+// authenticate returns the CredHub actor for a request authenticated with a
+// client certificate or a token issued by this server.
+func (f *FakeServer) authenticate(r *http.Request) (string, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		for _, unit := range r.TLS.VerifiedChains[0][0].Subject.OrganizationalUnit {
+			if strings.HasPrefix(unit, "app:") {
+				return "mtls-" + unit, true
+			}
+		}
+		return "", false
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < len("bearer ") || !strings.EqualFold(authorization[:len("bearer ")], "bearer ") {
+		return "", false
+	}
+	claims, ok := f.verifyToken(strings.TrimSpace(authorization[len("bearer "):]))
+	if !ok {
+		return "", false
+	}
+	return "uaa-user:" + claims["user_id"].(string), true
+}
+
+func (f *FakeServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	username := ""
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		if r.PostForm.Get("username") != f.Username || r.PostForm.Get("password") != f.Password {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized", "error_description": "Bad credentials"})
+			return
+		}
+		username = f.Username
+	case "refresh_token":
+		claims, ok := f.verifyToken(r.PostForm.Get("refresh_token"))
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+			return
+		}
+		username = claims["user_name"].(string)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  f.issueToken(username, time.Hour),
+		"refresh_token": f.issueToken(username, 24*time.Hour),
+		"token_type":    "bearer",
+		"expires_in":    3600,
+		"scope":         "credhub.read credhub.write",
+		"jti":           newUUID(),
+	})
+}
+
+func (f *FakeServer) issueToken(username string, lifetime time.Duration) string {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"jti":        newUUID(),
+		"user_name":  username,
+		"user_id":    username,
+		"client_id":  "credhub_cli",
+		"grant_type": "password",
+		"scope":      []string{"credhub.read", "credhub.write"},
+		"iss":        f.URL + "/oauth/token",
+		"iat":        now.Unix(),
+		"exp":        now.Add(lifetime).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + f.sign(unsigned)
+}
+
+func (f *FakeServer) verifyToken(token string) (map[string]interface{}, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(f.sign(parts[0]+"."+parts[1]))) {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Now().Unix() > int64(exp) {
+		return nil, false
+	}
+	return claims, true
+}
+
+func (f *FakeServer) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, f.tokenKey)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type fakeWriteRequest struct {
+	Name       string               `json:"name"`
+	Type       string               `json:"type"`
+	Value      json.RawMessage      `json:"value"`
+	Parameters generationParameters `json:"parameters"`
+	Overwrite  *bool                `json:"overwrite"`
+	Mode       string               `json:"mode"`
+	Regenerate bool                 `json:"regenerate"`
+}
+
+func (w fakeWriteRequest) overwrite() bool {
+	if w.Mode != "" {
+		return w.Mode == "overwrite"
+	}
+	return w.Overwrite != nil && *w.Overwrite
+}
+
+func (f *FakeServer) handleApi(w http.ResponseWriter, r *http.Request, actor string) {
+	switch {
+	case r.URL.Path == "/api/v1/data" && r.Method == "GET":
+		f.handleGet(w, r, actor)
+	case r.URL.Path == "/api/v1/data" && r.Method == "PUT":
+		f.handleSet(w, r, actor)
+	case r.URL.Path == "/api/v1/data" && r.Method == "POST":
+		f.handleGenerate(w, r, actor)
+	case r.URL.Path == "/api/v1/regenerate" && r.Method == "POST":
+		request := fakeWriteRequest{}
+		if !readJSON(w, r, &request) {
+			return
+		}
+		respond(w, func() (interface{}, error) { return f.store.Regenerate(actor, request.Name) })
+	case r.URL.Path == "/api/v1/data" && r.Method == "DELETE":
+		if err := f.store.Delete(actor, r.URL.Query().Get("name")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/data/") && r.Method == "GET":
+		respond(w, func() (interface{}, error) {
+			return f.store.GetById(actor, strings.TrimPrefix(r.URL.Path, "/api/v1/data/"))
+		})
+	case r.URL.Path == "/api/v1/interpolate" && r.Method == "POST":
+		services := map[string][]map[string]interface{}{}
+		if !readJSON(w, r, &services) {
+			return
+		}
+		respond(w, func() (interface{}, error) { return services, f.store.Interpolate(actor, services) })
+	default:
+		writeError(w, fakeError{status: http.StatusMethodNotAllowed, message: "The request could not be fulfilled because the request path or body did not meet expectation. Please check the documentation for required formatting and retry your request."})
+	}
+}
+
+// handleBackup stands in for bbr, which backs up and restores CredHub's
+// database directly.
+func (f *FakeServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/fake/dump" && r.Method == "GET":
+		dump, err := f.store.Dump()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.Write(dump)
+	case r.URL.Path == "/fake/restore" && r.Method == "PUT":
+		dump, err := ioutil.ReadAll(r.Body)
+		if err == nil {
+			err = f.store.Restore(dump)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, fakeError{status: http.StatusNotFound, message: "The request could not be fulfilled because the resource could not be found."})
+	}
+}
+
+func (f *FakeServer) handleGet(w http.ResponseWriter, r *http.Request, actor string) {
+	query := r.URL.Query()
+	switch {
+	case query.Get("name") != "":
+		limit := -1
+		if query.Get("versions") != "" {
+			var err error
+			if limit, err = strconv.Atoi(query.Get("versions")); err != nil || limit < 0 {
+				writeError(w, badRequest("The query parameter versions must be a non-negative number."))
+				return
+			}
+		}
+		respond(w, func() (interface{}, error) {
+			versions, err := f.store.Get(actor, query.Get("name"), query.Get("current") == "true")
+			if limit >= 0 && limit < len(versions) {
+				versions = versions[:limit]
+			}
+			return map[string]interface{}{"data": versions}, err
+		})
+	case query.Get("name-like") != "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"credentials": f.store.FindByNameLike(actor, query.Get("name-like"))})
+	case query.Get("path") != "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"credentials": f.store.FindByPath(actor, query.Get("path"))})
+	case query.Get("paths") == "true":
+		paths := []map[string]string{}
+		for _, p := range f.store.Paths(actor) {
+			paths = append(paths, map[string]string{"path": p})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"paths": paths})
+	default:
+		writeError(w, badRequest("The query parameter name is required for this request."))
+	}
+}
+
+func (f *FakeServer) handleSet(w http.ResponseWriter, r *http.Request, actor string) {
+	request := fakeWriteRequest{}
+	if !readJSON(w, r, &request) {
+		return
+	}
+	// Sets are unconditional unless the client asks otherwise.
+	overwrite := true
+	if request.Overwrite != nil || request.Mode != "" {
+		overwrite = request.overwrite()
+	}
+	respond(w, func() (interface{}, error) {
+		return f.store.Set(actor, request.Name, request.Type, request.Value, overwrite)
+	})
+}
+
+func (f *FakeServer) handleGenerate(w http.ResponseWriter, r *http.Request, actor string) {
+	request := fakeWriteRequest{}
+	if !readJSON(w, r, &request) {
+		return
+	}
+	if request.Regenerate {
+		respond(w, func() (interface{}, error) { return f.store.Regenerate(actor, request.Name) })
+		return
+	}
+
+	// Older CLIs send the username for user credentials in the value rather than the parameters.
+	if request.Type == "user" && len(request.Value) > 0 && request.Parameters.Username == "" {
+		value := fakeUserValue{}
+		json.Unmarshal(request.Value, &value)
+		request.Parameters.Username = value.Username
+	}
+	respond(w, func() (interface{}, error) {
+		return f.store.Generate(actor, request.Name, request.Type, request.Parameters, request.overwrite())
+	})
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, badRequest("The request could not be fulfilled because the request path or body did not meet expectation. Please check the documentation for required formatting and retry your request."))
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, action func() (interface{}, error)) {
+	result, err := action()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if apiErr, ok := err.(fakeError); ok {
+		status = apiErr.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// certificateUnknownListener makes rejected client certificates fail with the
+// certificate_unknown alert CredHub sends, instead of the more specific alerts
+// (bad_certificate, certificate_expired, unknown_ca) Go's TLS server uses.
+type certificateUnknownListener struct {
+	net.Listener
+}
+
+func (l certificateUnknownListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return certificateUnknownConn{conn}, nil
+}
+
+type certificateUnknownConn struct {
+	net.Conn
+}
+
+func (c certificateUnknownConn) Write(b []byte) (int, error) {
+	const (
+		recordTypeAlert         = 21
+		alertLevelError         = 2
+		alertBadCertificate     = 42
+		alertCertificateExpired = 45
+		alertCertificateUnknown = 46
+		alertUnknownCA          = 48
+	)
+
+	if len(b) == 7 && b[0] == recordTypeAlert && b[5] == alertLevelError {
+		switch b[6] {
+		case alertBadCertificate, alertCertificateExpired, alertUnknownCA:
+			rewritten := append([]byte{}, b...)
+			rewritten[6] = alertCertificateUnknown
+			return c.Conn.Write(rewritten)
+		}
+	}
+	return c.Conn.Write(b)
+}
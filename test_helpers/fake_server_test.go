@@ -0,0 +1,64 @@
+package test_helpers_test
+
+import (
+	"net/http"
+	"path"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	"github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers/client_certs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the fake server", func() {
+	var (
+		fake *FakeServer
+		cfg  Config
+	)
+
+	BeforeEach(func() {
+		var err error
+		fake, err = NewFakeServer("admin", "admin-password", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg = Config{
+			ApiUrl:         fake.URL,
+			ApiUsername:    fake.Username,
+			ApiPassword:    fake.Password,
+			CredentialRoot: fake.CredentialRoot,
+			UAACa:          path.Join(fake.CredentialRoot, "server_ca_cert.pem"),
+		}
+	})
+
+	AfterEach(func() {
+		fake.Close()
+	})
+
+	Describe("backing up and restoring", func() {
+		It("lets the UAA admin dump and restore the store", func() {
+			admin, err := NewTokenClient(cfg)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, dump, err := admin.Send("GET", "/fake/dump", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, _, err = admin.Send("PUT", "/fake/restore", dump)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(http.StatusNoContent))
+		})
+
+		It("does not let an application dump or restore the store", func() {
+			app, err := NewFixtureClient(cfg, client_certs.ForApp(client_certs.NewGuid()))
+			Expect(err).NotTo(HaveOccurred())
+
+			status, _, err := app.Send("GET", "/fake/dump", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(http.StatusForbidden))
+
+			status, _, err = app.Send("PUT", "/fake/restore", []byte(`{"versions": []}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(http.StatusForbidden))
+		})
+	})
+})
@@ -0,0 +1,650 @@
+package test_helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	credentialNotFoundError = "The request could not be completed because the credential does not exist or you do not have sufficient authorization."
+	typeMismatchError       = "The credential type cannot be modified. Please delete the credential if you wish to create it with a different type."
+)
+
+// fakeError is an API error with the HTTP status the fake server responds with.
+type fakeError struct {
+	status  int
+	message string
+}
+
+func (e fakeError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) error {
+	return fakeError{status: 400, message: message}
+}
+
+func notFound() error {
+	return fakeError{status: 404, message: credentialNotFoundError}
+}
+
+type fakeUserValue struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"password_hash"`
+}
+
+type fakeKeyValue struct {
+	PublicKey            string `json:"public_key"`
+	PrivateKey           string `json:"private_key"`
+	PublicKeyFingerprint string `json:"public_key_fingerprint,omitempty"`
+}
+
+type fakeCertificateValue struct {
+	Ca          string `json:"ca"`
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+}
+
+type fakeCredential struct {
+	Id               string      `json:"id"`
+	Name             string      `json:"name"`
+	Type             string      `json:"type"`
+	Value            interface{} `json:"value"`
+	VersionCreatedAt string      `json:"version_created_at"`
+
+	caName     string
+	parameters *generationParameters
+}
+
+type fakeFoundCredential struct {
+	Name             string `json:"name"`
+	VersionCreatedAt string `json:"version_created_at"`
+}
+
+// fakeStore holds every version of every credential, newest version last.
+// Like CredHub with permissions enforced, only the actor that created a
+// credential may use it; to everyone else it does not exist.
+type fakeStore struct {
+	sync.Mutex
+	versions map[string][]*fakeCredential
+	byId     map[string]*fakeCredential
+	owners   map[string]string
+
+	// encryptionKey encrypts values in dumps, as CredHub's encryption
+	// provider does in its database.
+	encryptionKey []byte
+}
+
+func newFakeStore() *fakeStore {
+	encryptionKey := make([]byte, 32)
+	rand.Read(encryptionKey)
+
+	return &fakeStore{
+		versions:      map[string][]*fakeCredential{},
+		byId:          map[string]*fakeCredential{},
+		owners:        map[string]string{},
+		encryptionKey: encryptionKey,
+	}
+}
+
+func normalizeName(name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
+
+func storeKey(name string) string {
+	return strings.ToLower(normalizeName(name))
+}
+
+func (s *fakeStore) current(name string) *fakeCredential {
+	versions := s.versions[storeKey(name)]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// permitted reports whether actor may use the named credential.
+func (s *fakeStore) permitted(name, actor string) bool {
+	return s.owners[storeKey(name)] == actor
+}
+
+// currentFor is current for a credential actor may use.
+func (s *fakeStore) currentFor(name, actor string) *fakeCredential {
+	if !s.permitted(name, actor) {
+		return nil
+	}
+	return s.current(name)
+}
+
+func (s *fakeStore) add(credential *fakeCredential, actor string) *fakeCredential {
+	credential.Id = newUUID()
+	credential.Name = normalizeName(credential.Name)
+	credential.VersionCreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	key := storeKey(credential.Name)
+	if _, ok := s.owners[key]; !ok {
+		s.owners[key] = actor
+	}
+	s.versions[key] = append(s.versions[key], credential)
+	s.byId[credential.Id] = credential
+	return credential
+}
+
+// existing returns the current version when a write should not replace it.
+func (s *fakeStore) existing(name, credentialType, actor string, overwrite bool) (*fakeCredential, error) {
+	current := s.current(name)
+	if current == nil {
+		return nil, nil
+	}
+	if !s.permitted(name, actor) {
+		return nil, notFound()
+	}
+	if current.Type != credentialType {
+		return nil, badRequest(typeMismatchError)
+	}
+	if !overwrite {
+		return current, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) Set(actor, name, credentialType string, rawValue json.RawMessage, overwrite bool) (*fakeCredential, error) {
+	if name == "" {
+		return nil, badRequest("A credential name must be provided. Please validate your input and retry your request.")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if existing, err := s.existing(name, credentialType, actor, overwrite); existing != nil || err != nil {
+		return existing, err
+	}
+
+	credential := &fakeCredential{Name: name, Type: credentialType}
+	if err := s.parseValue(credential, rawValue, actor); err != nil {
+		return nil, err
+	}
+	return s.add(credential, actor), nil
+}
+
+// settableKeys are the keys CredHub accepts in the value of a set, for the
+// types whose value is an object with fixed keys. Gets return more, such as a
+// user's password hash and an SSH key's fingerprint.
+var settableKeys = map[string][]string{
+	"user":        {"username", "password"},
+	"ssh":         {"public_key", "private_key"},
+	"rsa":         {"public_key", "private_key"},
+	"certificate": {"ca", "ca_name", "certificate", "private_key"},
+}
+
+// checkKeys rejects an object value with a key that is not known.
+func checkKeys(rawValue json.RawMessage, known []string) error {
+	var value map[string]json.RawMessage
+	if json.Unmarshal(rawValue, &value) != nil {
+		return nil
+	}
+	isKnown := map[string]bool{}
+	for _, key := range known {
+		isKnown[key] = true
+	}
+	for key := range value {
+		if !isKnown[key] {
+			return badRequest(fmt.Sprintf("The request includes an unrecognized parameter '%s'. Please update or remove this field and retry your request.", key))
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) parseValue(credential *fakeCredential, rawValue json.RawMessage, actor string) error {
+	if len(rawValue) == 0 || string(rawValue) == "null" {
+		return badRequest("A non-empty value must be specified for the credential. Please validate your input and retry your request.")
+	}
+
+	if known, ok := settableKeys[credential.Type]; ok {
+		if err := checkKeys(rawValue, known); err != nil {
+			return err
+		}
+	}
+
+	switch credential.Type {
+	case "value", "password":
+		var value string
+		if err := json.Unmarshal(rawValue, &value); err != nil || value == "" {
+			return badRequest("A non-empty value must be specified for the credential. Please validate your input and retry your request.")
+		}
+		credential.Value = value
+	case "json":
+		var value map[string]interface{}
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			return badRequest("The request could not be fulfilled because the request path or body did not meet expectation. Please check the documentation for required formatting and retry your request.")
+		}
+		credential.Value = value
+	case "user":
+		var value fakeUserValue
+		if err := json.Unmarshal(rawValue, &value); err != nil || value.Password == "" {
+			return badRequest("A password value must be specified for the credential. Please validate your input and retry your request.")
+		}
+		value.PasswordHash = sha512Crypt(value.Password)
+		credential.Value = value
+	case "ssh", "rsa":
+		var value fakeKeyValue
+		if err := json.Unmarshal(rawValue, &value); err != nil || (value.PublicKey == "" && value.PrivateKey == "") {
+			return badRequest("At least one key value must be set. Please validate your input and retry your request.")
+		}
+		credential.Value = value
+	case "certificate":
+		var value struct {
+			fakeCertificateValue
+			CaName string `json:"ca_name"`
+		}
+		if err := json.Unmarshal(rawValue, &value); err != nil ||
+			(value.Ca == "" && value.CaName == "" && value.Certificate == "" && value.PrivateKey == "") {
+			return badRequest("At least one certificate attribute must be set. Please validate your input and retry your request.")
+		}
+		if value.CaName != "" {
+			ca := s.currentFor(value.CaName, actor)
+			if ca == nil || ca.Type != "certificate" {
+				return badRequest("The request could not be completed because the CA does not exist or you do not have sufficient authorization.")
+			}
+			value.Ca = ca.Value.(fakeCertificateValue).Certificate
+			credential.caName = ca.Name
+		}
+		credential.Value = value.fakeCertificateValue
+	default:
+		return badRequest("The request does not include a valid type. Valid values include 'value', 'json', 'password', 'user', 'certificate', 'ssh' and 'rsa'.")
+	}
+	return nil
+}
+
+func (s *fakeStore) Generate(actor, name, credentialType string, params generationParameters, overwrite bool) (*fakeCredential, error) {
+	if name == "" {
+		return nil, badRequest("A credential name must be provided. Please validate your input and retry your request.")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if existing, err := s.existing(name, credentialType, actor, overwrite); existing != nil || err != nil {
+		return existing, err
+	}
+	return s.generate(actor, name, credentialType, params)
+}
+
+func (s *fakeStore) Regenerate(actor, name string) (*fakeCredential, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	current := s.currentFor(name, actor)
+	if current == nil {
+		return nil, notFound()
+	}
+	if current.parameters == nil {
+		return nil, badRequest(fmt.Sprintf("The %s could not be regenerated because the value was statically set. Only generated credentials may be regenerated.", current.Type))
+	}
+	return s.generate(actor, current.Name, current.Type, *current.parameters)
+}
+
+func (s *fakeStore) generate(actor, name, credentialType string, params generationParameters) (*fakeCredential, error) {
+	var value interface{}
+	var caName string
+	var err error
+
+	switch credentialType {
+	case "password":
+		value, err = generatePassword(params)
+	case "user":
+		username := params.Username
+		if username == "" {
+			username = generateUsername()
+		}
+		var password string
+		password, err = generatePassword(params)
+		value = fakeUserValue{Username: username, Password: password, PasswordHash: sha512Crypt(password)}
+	case "rsa":
+		value, err = generateRSA(params)
+	case "ssh":
+		value, err = generateSSH(params)
+	case "certificate":
+		value, caName, err = s.generateCertificate(params, actor)
+	case "value", "json":
+		err = badRequest("Credentials of this type cannot be generated. Please adjust the credential type and retry your request.")
+	default:
+		err = badRequest("The request does not include a valid type. Valid values for generate include 'password', 'user', 'certificate', 'ssh' and 'rsa'.")
+	}
+	if err != nil {
+		if _, ok := err.(fakeError); !ok {
+			err = badRequest(err.Error())
+		}
+		return nil, err
+	}
+
+	return s.add(&fakeCredential{Name: name, Type: credentialType, Value: value, caName: caName, parameters: &params}, actor), nil
+}
+
+// generateCertificate signs with the current version of the named CA, returning its full name.
+func (s *fakeStore) generateCertificate(params generationParameters, actor string) (fakeCertificateValue, string, error) {
+	if params.Ca == "" && !params.SelfSign && !params.IsCa {
+		return fakeCertificateValue{}, "", badRequest("Certificates must either be self-signed or signed by a CA. Please provide a CA name or set the self-sign flag.")
+	}
+	if params.Ca == "" {
+		value, err := generateCertificate(params, nil)
+		return value, "", err
+	}
+
+	ca := s.currentFor(params.Ca, actor)
+	if ca == nil || ca.Type != "certificate" {
+		return fakeCertificateValue{}, "", badRequest("The request could not be completed because the CA does not exist or you do not have sufficient authorization.")
+	}
+	authority, err := parseSigningAuthority(ca.Value.(fakeCertificateValue))
+	if err != nil {
+		return fakeCertificateValue{}, "", err
+	}
+
+	value, err := generateCertificate(params, authority)
+	return value, ca.Name, err
+}
+
+func (s *fakeStore) Get(actor, name string, current bool) ([]*fakeCredential, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	versions := s.versions[storeKey(name)]
+	if len(versions) == 0 || !s.permitted(name, actor) {
+		return nil, notFound()
+	}
+	if current {
+		return versions[len(versions)-1:], nil
+	}
+
+	newestFirst := make([]*fakeCredential, len(versions))
+	for i, version := range versions {
+		newestFirst[len(versions)-1-i] = version
+	}
+	return newestFirst, nil
+}
+
+func (s *fakeStore) GetById(actor, id string) (*fakeCredential, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	credential, ok := s.byId[id]
+	if !ok || !s.permitted(credential.Name, actor) {
+		return nil, notFound()
+	}
+	return credential, nil
+}
+
+func (s *fakeStore) Delete(actor, name string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := storeKey(name)
+	versions, ok := s.versions[key]
+	if !ok || !s.permitted(name, actor) {
+		return notFound()
+	}
+	for _, version := range versions {
+		delete(s.byId, version.Id)
+	}
+	delete(s.versions, key)
+	delete(s.owners, key)
+	return nil
+}
+
+// Find returns the current version of every credential actor may use that is
+// accepted by match, newest first.
+func (s *fakeStore) Find(actor string, match func(name string) bool) []fakeFoundCredential {
+	s.Lock()
+	defer s.Unlock()
+
+	found := []fakeFoundCredential{}
+	for key, versions := range s.versions {
+		current := versions[len(versions)-1]
+		if s.owners[key] == actor && match(current.Name) {
+			found = append(found, fakeFoundCredential{Name: current.Name, VersionCreatedAt: current.VersionCreatedAt})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].VersionCreatedAt == found[j].VersionCreatedAt {
+			return found[i].Name < found[j].Name
+		}
+		return found[i].VersionCreatedAt > found[j].VersionCreatedAt
+	})
+	return found
+}
+
+func (s *fakeStore) FindByNameLike(actor, nameLike string) []fakeFoundCredential {
+	nameLike = strings.ToLower(nameLike)
+	return s.Find(actor, func(name string) bool {
+		return strings.Contains(strings.ToLower(name), nameLike)
+	})
+}
+
+func (s *fakeStore) FindByPath(actor, path string) []fakeFoundCredential {
+	path = strings.ToLower(normalizeName(path))
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return s.Find(actor, func(name string) bool {
+		return strings.HasPrefix(strings.ToLower(name), path)
+	})
+}
+
+// Paths returns every path containing a credential actor may use.
+func (s *fakeStore) Paths(actor string) []string {
+	s.Lock()
+	defer s.Unlock()
+
+	unique := map[string]bool{}
+	for key, versions := range s.versions {
+		if s.owners[key] != actor {
+			continue
+		}
+		name := versions[len(versions)-1].Name
+		for i := 1; i < len(name); i++ {
+			if name[i] == '/' {
+				unique[name[:i+1]] = true
+			}
+		}
+	}
+
+	paths := []string{}
+	for path := range unique {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Interpolate replaces every `credhub-ref` in a VCAP_SERVICES document with the referenced JSON credential.
+func (s *fakeStore) Interpolate(actor string, services map[string][]map[string]interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, instances := range services {
+		for _, instance := range instances {
+			credentials, ok := instance["credentials"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ref, ok := credentials["credhub-ref"].(string)
+			if !ok {
+				continue
+			}
+
+			name := strings.TrimSuffix(strings.TrimPrefix(ref, "(("), "))")
+			current := s.currentFor(name, actor)
+			if current == nil {
+				return notFound()
+			}
+			if current.Type != "json" {
+				return badRequest(fmt.Sprintf("The credential '%s' is not the expected type. A credhub-ref credential must be of type 'JSON'.", current.Name))
+			}
+			instance["credentials"] = current.Value
+		}
+	}
+	return nil
+}
+
+// fakeDump is the fake's equivalent of a database dump, as written by Dump
+// and read by Restore. Values are encrypted, like in CredHub's database.
+type fakeDump struct {
+	Versions []fakeDumpedVersion `json:"versions"`
+	Owners   map[string]string   `json:"owners"`
+}
+
+type fakeDumpedVersion struct {
+	Id               string                `json:"id"`
+	Name             string                `json:"name"`
+	Type             string                `json:"type"`
+	EncryptedValue   []byte                `json:"encrypted_value"`
+	Nonce            []byte                `json:"nonce"`
+	VersionCreatedAt string                `json:"version_created_at"`
+	CaName           string                `json:"ca_name,omitempty"`
+	Parameters       *generationParameters `json:"parameters,omitempty"`
+}
+
+// Dump returns every version of every credential, oldest first, and their owners.
+func (s *fakeStore) Dump() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	keys := []string{}
+	for key := range s.versions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dump := fakeDump{Versions: []fakeDumpedVersion{}, Owners: s.owners}
+	for _, key := range keys {
+		for _, version := range s.versions[key] {
+			value, err := json.Marshal(version.Value)
+			if err != nil {
+				return nil, err
+			}
+			encrypted, nonce, err := s.encrypt(value)
+			if err != nil {
+				return nil, err
+			}
+			dump.Versions = append(dump.Versions, fakeDumpedVersion{
+				Id:               version.Id,
+				Name:             version.Name,
+				Type:             version.Type,
+				EncryptedValue:   encrypted,
+				Nonce:            nonce,
+				VersionCreatedAt: version.VersionCreatedAt,
+				CaName:           version.caName,
+				Parameters:       version.parameters,
+			})
+		}
+	}
+	return json.Marshal(dump)
+}
+
+// Restore replaces everything in the store with a dump, like restoring a
+// database from a backup.
+func (s *fakeStore) Restore(encoded []byte) error {
+	dump := fakeDump{}
+	if err := json.Unmarshal(encoded, &dump); err != nil {
+		return badRequest(fmt.Sprintf("The dump could not be read: %s", err))
+	}
+
+	restored := newFakeStore()
+	for key, owner := range dump.Owners {
+		restored.owners[key] = owner
+	}
+	for _, dumped := range dump.Versions {
+		plaintext, err := s.decrypt(dumped.EncryptedValue, dumped.Nonce)
+		if err != nil {
+			return badRequest(fmt.Sprintf("The dump of %s could not be decrypted: %s", dumped.Name, err))
+		}
+		value, err := decodeDumpedValue(dumped.Type, plaintext)
+		if err != nil {
+			return badRequest(fmt.Sprintf("The dump of %s could not be read: %s", dumped.Name, err))
+		}
+		credential := &fakeCredential{
+			Id:               dumped.Id,
+			Name:             dumped.Name,
+			Type:             dumped.Type,
+			Value:            value,
+			VersionCreatedAt: dumped.VersionCreatedAt,
+			caName:           dumped.CaName,
+			parameters:       dumped.Parameters,
+		}
+		key := storeKey(credential.Name)
+		restored.versions[key] = append(restored.versions[key], credential)
+		restored.byId[credential.Id] = credential
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.versions, s.byId, s.owners = restored.versions, restored.byId, restored.owners
+	return nil
+}
+
+func (s *fakeStore) encrypt(plaintext []byte) (ciphertext, nonce []byte, err error) {
+	aead, err := s.cipher()
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, nil), nonce, nil
+}
+
+func (s *fakeStore) decrypt(ciphertext, nonce []byte) ([]byte, error) {
+	aead, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func (s *fakeStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decodeDumpedValue decodes a value into the type the store keeps it as.
+func decodeDumpedValue(credentialType string, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch credentialType {
+	case "value", "password":
+		var value string
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "json":
+		var value map[string]interface{}
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "user":
+		var value fakeUserValue
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "ssh", "rsa":
+		var value fakeKeyValue
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	case "certificate":
+		var value fakeCertificateValue
+		err = json.Unmarshal(raw, &value)
+		return value, err
+	}
+	return nil, fmt.Errorf("unknown type %q", credentialType)
+}
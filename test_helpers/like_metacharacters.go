@@ -0,0 +1,52 @@
+package test_helpers
+
+// LikeMetacharacterNames are seeded under a unique path for the find specs.
+// Each name containing a SQL LIKE metacharacter has a neighbour that the
+// metacharacter would match if CredHub passed it on as a wildcard.
+var LikeMetacharacterNames = []string{
+	"%percent", "xpercent",
+	"_underscore", "xunderscore",
+	`\backslash`, "backslash",
+	"[b]racket", "bracket",
+	"MixedCase",
+	"%dir/credential", "_dir/credential", `\dir/credential`, "[d]ir/credential",
+	"dir/credential", "xdir/credential", "MixedDir/credential",
+}
+
+// LikeQuery is a find query, relative to the seeded path, and the seeded
+// names it matches when taken literally.
+type LikeQuery struct {
+	Description string
+	Query       string
+	Matches     []string
+}
+
+// MatchesUnder returns the full names of the matches when the names were
+// seeded under base.
+func (q LikeQuery) MatchesUnder(base string) []string {
+	names := []string{}
+	for _, name := range q.Matches {
+		names = append(names, base+"/"+name)
+	}
+	return names
+}
+
+// NameLikeQueries are compared against full names, so each starts at a
+// segment of the seeded path to keep out the names of neighbouring specs.
+var NameLikeQueries = []LikeQuery{
+	{"a percent sign", "%percent", []string{"%percent"}},
+	{"a lone percent sign", "%", []string{"%percent", "%dir/credential"}},
+	{"an underscore", "_underscore", []string{"_underscore"}},
+	{"a lone underscore", "_", []string{"_underscore", "_dir/credential"}},
+	{"a backslash", `\backslash`, []string{`\backslash`}},
+	{"a square bracket", "[b]racket", []string{"[b]racket"}},
+	{"different case", "mIXEDcASE", []string{"MixedCase"}},
+}
+
+var PathQueries = []LikeQuery{
+	{"a percent sign", "%dir", []string{"%dir/credential"}},
+	{"an underscore", "_dir", []string{"_dir/credential"}},
+	{"a backslash", `\dir`, []string{`\dir/credential`}},
+	{"a square bracket", "[d]ir", []string{"[d]ir/credential"}},
+	{"different case", "mixeddir", []string{"MixedDir/credential"}},
+}
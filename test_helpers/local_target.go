@@ -0,0 +1,79 @@
+package test_helpers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/gomega"
+)
+
+var (
+	localServer *FakeServer
+	localTarget *Config
+)
+
+type suiteData struct {
+	CommandPath string  `json:"command_path"`
+	LocalTarget *Config `json:"local_target,omitempty"`
+}
+
+// StartSuite runs on the first Ginkgo node. When test_config.json sets
+// "local", it starts a FakeServer for every node to share. The returned data
+// must be passed to JoinSuite on all nodes.
+func StartSuite(commandPath string) []byte {
+	cfg, err := LoadConfig()
+	Expect(err).NotTo(HaveOccurred())
+
+	data := suiteData{CommandPath: commandPath}
+	if cfg.Local {
+		username, password := cfg.ApiUsername, cfg.ApiPassword
+		if username == "" {
+			username, password = "credhub", "password"
+		}
+
+		localServer, err = NewFakeServer(username, password, cfg.CredentialRoot)
+		Expect(err).NotTo(HaveOccurred())
+
+		data.LocalTarget = &Config{
+			ApiUrl:         localServer.URL,
+			ApiUsername:    localServer.Username,
+			ApiPassword:    localServer.Password,
+			CredentialRoot: localServer.CredentialRoot,
+			UAACa:          localServer.CredentialRoot + "/server_ca_cert.pem",
+			Local:          true,
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	Expect(err).NotTo(HaveOccurred())
+	return encoded
+}
+
+// JoinSuite runs on every Ginkgo node with the data returned by StartSuite.
+func JoinSuite(data []byte) {
+	decoded := suiteData{}
+	Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+
+	CommandPath = decoded.CommandPath
+	localTarget = decoded.LocalTarget
+}
+
+// StopSuite stops the FakeServer started by StartSuite, if any.
+func StopSuite() {
+	if localServer != nil {
+		localServer.Close()
+		localServer = nil
+	}
+}
+
+func applyLocalTarget(cfg Config) Config {
+	if localTarget == nil {
+		return cfg
+	}
+
+	cfg.ApiUrl = localTarget.ApiUrl
+	cfg.ApiUsername = localTarget.ApiUsername
+	cfg.ApiPassword = localTarget.ApiPassword
+	cfg.CredentialRoot = localTarget.CredentialRoot
+	cfg.UAACa = localTarget.UAACa
+	return cfg
+}
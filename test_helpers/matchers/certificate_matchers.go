@@ -0,0 +1,302 @@
+// Package matchers provides Gomega matchers for the *x509.Certificate values
+// parsed out of CredHub certificate credentials.
+package matchers
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/types"
+)
+
+// KeyUsageNames are the key usages CredHub generates, with their names in the
+// API, in the order its error messages list them.
+var KeyUsageNames = []struct {
+	Usage x509.KeyUsage
+	Name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digital_signature"},
+	{x509.KeyUsageContentCommitment, "non_repudiation"},
+	{x509.KeyUsageKeyEncipherment, "key_encipherment"},
+	{x509.KeyUsageDataEncipherment, "data_encipherment"},
+	{x509.KeyUsageKeyAgreement, "key_agreement"},
+	{x509.KeyUsageCertSign, "key_cert_sign"},
+	{x509.KeyUsageCRLSign, "crl_sign"},
+	{x509.KeyUsageEncipherOnly, "encipher_only"},
+	{x509.KeyUsageDecipherOnly, "decipher_only"},
+}
+
+// ExtKeyUsageNames are the extended key usages CredHub generates, likewise.
+var ExtKeyUsageNames = []struct {
+	Usage x509.ExtKeyUsage
+	Name  string
+}{
+	{x509.ExtKeyUsageClientAuth, "client_auth"},
+	{x509.ExtKeyUsageServerAuth, "server_auth"},
+	{x509.ExtKeyUsageCodeSigning, "code_signing"},
+	{x509.ExtKeyUsageEmailProtection, "email_protection"},
+	{x509.ExtKeyUsageTimeStamping, "timestamping"},
+}
+
+// HaveCommonName succeeds when the certificate's subject has the given common name.
+func HaveCommonName(commonName string) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have common name %q", commonName),
+		match: func(cert *x509.Certificate) bool {
+			return cert.Subject.CommonName == commonName
+		},
+	}
+}
+
+// HaveIssuer succeeds when the certificate's issuer has the given common name.
+func HaveIssuer(commonName string) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to be issued by %q", commonName),
+		match: func(cert *x509.Certificate) bool {
+			return cert.Issuer.CommonName == commonName
+		},
+	}
+}
+
+// BeCA succeeds when the certificate's basic constraints mark it as a CA.
+func BeCA() types.GomegaMatcher {
+	return &certificateMatcher{
+		description: "to be a CA",
+		match: func(cert *x509.Certificate) bool {
+			return cert.IsCA
+		},
+	}
+}
+
+// BeSignedBy succeeds when the certificate's signature verifies against the
+// public key of ca. Pass the certificate itself to check it is self-signed.
+func BeSignedBy(ca *x509.Certificate) types.GomegaMatcher {
+	description := "to be signed by <nil>"
+	if ca != nil {
+		description = fmt.Sprintf("to be signed by %q (subject key id %x)", ca.Subject.CommonName, ca.SubjectKeyId)
+	}
+	return &certificateMatcher{
+		description: description,
+		match: func(cert *x509.Certificate) bool {
+			return ca != nil && ca.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+		},
+	}
+}
+
+// HaveKeyUsage succeeds when the certificate has exactly the given key usages.
+func HaveKeyUsage(usages ...x509.KeyUsage) types.GomegaMatcher {
+	var expected x509.KeyUsage
+	for _, usage := range usages {
+		expected |= usage
+	}
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have key usage %s", keyUsageString(expected)),
+		match: func(cert *x509.Certificate) bool {
+			return cert.KeyUsage == expected
+		},
+	}
+}
+
+// HaveExtKeyUsage succeeds when the certificate has exactly the given
+// extended key usages, in any order.
+func HaveExtKeyUsage(usages ...x509.ExtKeyUsage) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have extended key usage %s", extKeyUsageString(usages)),
+		match: func(cert *x509.Certificate) bool {
+			return sameElements(cert.ExtKeyUsage, usages)
+		},
+	}
+}
+
+// HaveValidityDays succeeds when the certificate is valid for exactly the given number of days.
+func HaveValidityDays(days int) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to be valid for %d days", days),
+		match: func(cert *x509.Certificate) bool {
+			return cert.NotAfter.Sub(cert.NotBefore) == time.Duration(days)*24*time.Hour
+		},
+	}
+}
+
+// HaveKeyBits succeeds when the certificate has an RSA public key of the given size.
+func HaveKeyBits(bits int) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have a %d bit RSA key", bits),
+		match: func(cert *x509.Certificate) bool {
+			return keyBits(cert) == bits
+		},
+	}
+}
+
+// HaveSANs succeeds when the certificate's DNS and IP subject alternative
+// names are exactly the given names, in any order.
+func HaveSANs(names ...string) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have subject alternative names %v", names),
+		match: func(cert *x509.Certificate) bool {
+			return sameElements(alternativeNames(cert), names)
+		},
+	}
+}
+
+// HaveSubject succeeds when the certificate's subject has exactly the common
+// name, organization, organization unit, locality, state and country of
+// subject.
+func HaveSubject(subject pkix.Name) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have subject %q", subject.String()),
+		match: func(cert *x509.Certificate) bool {
+			actual := cert.Subject
+			return actual.CommonName == subject.CommonName &&
+				reflect.DeepEqual(nonNil(actual.Organization), nonNil(subject.Organization)) &&
+				reflect.DeepEqual(nonNil(actual.OrganizationalUnit), nonNil(subject.OrganizationalUnit)) &&
+				reflect.DeepEqual(nonNil(actual.Locality), nonNil(subject.Locality)) &&
+				reflect.DeepEqual(nonNil(actual.Province), nonNil(subject.Province)) &&
+				reflect.DeepEqual(nonNil(actual.Country), nonNil(subject.Country))
+		},
+	}
+}
+
+// HaveDNSNames succeeds when the certificate's DNS subject alternative names
+// are exactly the given names, in any order.
+func HaveDNSNames(names ...string) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have DNS names %v", names),
+		match: func(cert *x509.Certificate) bool {
+			return sameElements(cert.DNSNames, names)
+		},
+	}
+}
+
+// HaveIPAddresses succeeds when the certificate's IP subject alternative names
+// are exactly the given addresses, in any order.
+func HaveIPAddresses(addresses ...string) types.GomegaMatcher {
+	return &certificateMatcher{
+		description: fmt.Sprintf("to have IP addresses %v", addresses),
+		match: func(cert *x509.Certificate) bool {
+			actual := []string{}
+			for _, ip := range cert.IPAddresses {
+				actual = append(actual, ip.String())
+			}
+			expected := []string{}
+			for _, address := range addresses {
+				expected = append(expected, net.ParseIP(address).String())
+			}
+			return sameElements(actual, expected)
+		},
+	}
+}
+
+type certificateMatcher struct {
+	description string
+	match       func(cert *x509.Certificate) bool
+}
+
+func (m *certificateMatcher) Match(actual interface{}) (bool, error) {
+	cert, ok := actual.(*x509.Certificate)
+	if !ok || cert == nil {
+		return false, fmt.Errorf("Certificate matcher expects a non-nil *x509.Certificate.  Got:\n%s", format.Object(actual, 1))
+	}
+	return m.match(cert), nil
+}
+
+func (m *certificateMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected certificate\n%s%s", describe(actual.(*x509.Certificate)), m.description)
+}
+
+func (m *certificateMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected certificate\n%snot %s", describe(actual.(*x509.Certificate)), m.description)
+}
+
+func describe(cert *x509.Certificate) string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "    subject:            %q\n", cert.Subject.String())
+	fmt.Fprintf(&out, "    issuer:             %q\n", cert.Issuer.String())
+	fmt.Fprintf(&out, "    is CA:              %t\n", cert.IsCA)
+	fmt.Fprintf(&out, "    key usage:          %s\n", keyUsageString(cert.KeyUsage))
+	fmt.Fprintf(&out, "    extended key usage: %s\n", extKeyUsageString(cert.ExtKeyUsage))
+	fmt.Fprintf(&out, "    validity:           %s to %s (%.4g days)\n",
+		cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339), cert.NotAfter.Sub(cert.NotBefore).Hours()/24)
+	fmt.Fprintf(&out, "    key bits:           %d\n", keyBits(cert))
+	fmt.Fprintf(&out, "    alternative names:  %v\n", alternativeNames(cert))
+	fmt.Fprintf(&out, "    subject key id:     %x\n", cert.SubjectKeyId)
+	fmt.Fprintf(&out, "    authority key id:   %x\n", cert.AuthorityKeyId)
+	return out.String()
+}
+
+func keyUsageString(usage x509.KeyUsage) string {
+	names := []string{}
+	for _, known := range KeyUsageNames {
+		if usage&known.Usage != 0 {
+			names = append(names, known.Name)
+		}
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func extKeyUsageString(usages []x509.ExtKeyUsage) string {
+	names := []string{}
+	for _, usage := range usages {
+		name := fmt.Sprintf("unknown(%d)", usage)
+		for _, known := range ExtKeyUsageNames {
+			if known.Usage == usage {
+				name = known.Name
+			}
+		}
+		names = append(names, name)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func keyBits(cert *x509.Certificate) int {
+	if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+		return key.N.BitLen()
+	}
+	return 0
+}
+
+func alternativeNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// sameElements compares two slices of the same element type, ignoring order.
+func sameElements(actual, expected interface{}) bool {
+	actualValue, expectedValue := reflect.ValueOf(actual), reflect.ValueOf(expected)
+	if actualValue.Len() != expectedValue.Len() {
+		return false
+	}
+
+	used := make([]bool, actualValue.Len())
+	for i := 0; i < expectedValue.Len(); i++ {
+		found := false
+		for j := 0; j < actualValue.Len(); j++ {
+			if !used[j] && actualValue.Index(j).Interface() == expectedValue.Index(i).Interface() {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
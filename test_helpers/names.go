@@ -0,0 +1,91 @@
+package test_helpers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/config"
+)
+
+const (
+	// RunIdEnv overrides the run ID, e.g. to find a CI build's credentials later.
+	RunIdEnv = "CREDHUB_RUN_ID"
+
+	credentialRoot = "acceptance"
+	maxSlugLength  = 40
+)
+
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// RunPath is the path all credentials named by this run are created under,
+// e.g. /acceptance/1500000000-4242.
+//
+// Every Ginkgo node of a run gets the same random seed from the ginkgo CLI,
+// which is also the parent of all of them, so together they identify the run
+// without the nodes having to talk to each other. The seed is only known once
+// flags are parsed, so names must be generated in spec and setup nodes rather
+// than while the spec tree is built.
+func RunPath() string {
+	id := os.Getenv(RunIdEnv)
+	if id == "" {
+		id = fmt.Sprintf("%d-%d", config.GinkgoConfig.RandomSeed, os.Getppid())
+	}
+	return "/" + path.Join(credentialRoot, id)
+}
+
+// NodePath is the path under RunPath all credentials named on this Ginkgo node
+// are created under, e.g. /acceptance/1500000000-4242/node-2.
+func NodePath() string {
+	return path.Join(RunPath(), fmt.Sprintf("node-%d", GinkgoParallelNode()))
+}
+
+// GenerateUniqueCredentialName returns a name, without a leading slash, under
+// RunPath, the Ginkgo node and the running spec, e.g.
+// acceptance/1500000000-4242/node-2/password-test-should-set-a-password/3f2a9c81d07e.
+func GenerateUniqueCredentialName() string {
+	return GenerateNestedCredentialName()
+}
+
+// GenerateNestedCredentialName is GenerateUniqueCredentialName with the given
+// path segments between the spec and the unique part of the name.
+func GenerateNestedCredentialName(segments ...string) string {
+	return path.Join(append(append([]string{specPath()}, segments...), randomSuffix())...)
+}
+
+// GenerateSpecialCharacterCredentialName is GenerateUniqueCredentialName with
+// the punctuation CredHub allows in names in its last segment.
+func GenerateSpecialCharacterCredentialName() string {
+	return path.Join(specPath(), "special.chars_in-name."+randomSuffix())
+}
+
+func specPath() string {
+	return strings.TrimPrefix(path.Join(NodePath(), specSlug()), "/")
+}
+
+// specSlug names the running spec, or "setup" for names generated while the
+// spec tree is built or in suite-level nodes.
+func specSlug() string {
+	slug := nonSlugCharacters.ReplaceAllString(strings.ToLower(CurrentGinkgoTestDescription().FullTestText), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		return "setup"
+	}
+	return slug
+}
+
+func randomSuffix() string {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(suffix)
+}
@@ -0,0 +1,252 @@
+package test_helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/gomega/format"
+	. "github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/types"
+)
+
+// Race runs the same kind of operation from many goroutines at once, usually
+// against a single credential.
+type Race struct {
+	Racers int
+
+	// Jitter delays each racer by a random duration up to Jitter once the
+	// start barrier opens.
+	Jitter time.Duration
+
+	// Stagger delays racer i by i*Stagger once the start barrier opens.
+	Stagger time.Duration
+
+	// NoBarrier starts each racer as soon as its goroutine runs, instead of
+	// holding them all until every one is ready.
+	NoBarrier bool
+}
+
+// RaceOutcome is what one racer got back. Session is only set for CLI racers.
+type RaceOutcome struct {
+	Racer      int
+	Credential Credential
+	Err        error
+	Session    *Session
+}
+
+type RaceOutcomes []RaceOutcome
+
+// Run calls operation once per racer, passing the racer's index, and returns
+// the outcomes in racer order.
+func (r Race) Run(operation func(racer int) RaceOutcome) RaceOutcomes {
+	outcomes := make(RaceOutcomes, r.Racers)
+
+	var ready, done sync.WaitGroup
+	start := make(chan struct{})
+	ready.Add(r.Racers)
+	done.Add(r.Racers)
+
+	for i := 0; i < r.Racers; i++ {
+		delay := time.Duration(i) * r.Stagger
+		if r.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(r.Jitter)))
+		}
+
+		go func(racer int, delay time.Duration) {
+			defer GinkgoRecover()
+			defer done.Done()
+
+			ready.Done()
+			if !r.NoBarrier {
+				<-start
+			}
+			time.Sleep(delay)
+
+			outcome := operation(racer)
+			outcome.Racer = racer
+			outcomes[racer] = outcome
+		}(i, delay)
+	}
+
+	ready.Wait()
+	close(start)
+	done.Wait()
+
+	return outcomes
+}
+
+// RaceCommand runs a CLI command for a racer. Commands that print a
+// credential are asked for JSON so that the outcome carries it; a non-zero
+// exit becomes the outcome's error, with the CLI's error output as message.
+func RaceCommand(args ...string) RaceOutcome {
+	printsCredential := len(args) > 0 && (args[0] == "get" || args[0] == "set" || args[0] == "generate" || args[0] == "regenerate")
+	if printsCredential {
+		args = append(args, "--output-json")
+	}
+
+	session := RunCommand(args...)
+	outcome := RaceOutcome{Session: session}
+
+	if session.ExitCode() != 0 {
+		outcome.Err = errors.New(strings.TrimSpace(string(session.Err.Contents()) + " " + string(session.Out.Contents())))
+	} else if printsCredential {
+		outcome.Err = json.Unmarshal(session.Out.Contents(), &outcome.Credential)
+	}
+	return outcome
+}
+
+// RaceApi wraps the result of a CredhubClient call for a racer.
+func RaceApi(credential Credential, err error) RaceOutcome {
+	return RaceOutcome{Credential: credential, Err: err}
+}
+
+// Succeeded returns the outcomes without an error.
+func (o RaceOutcomes) Succeeded() RaceOutcomes {
+	succeeded := RaceOutcomes{}
+	for _, outcome := range o {
+		if outcome.Err == nil {
+			succeeded = append(succeeded, outcome)
+		}
+	}
+	return succeeded
+}
+
+// Values returns the compacted JSON value of every successful outcome.
+func (o RaceOutcomes) Values() []string {
+	values := []string{}
+	for _, outcome := range o.Succeeded() {
+		values = append(values, compactValue(outcome.Credential.Value))
+	}
+	return values
+}
+
+// AllSucceed succeeds when no racer got an error.
+func AllSucceed() types.GomegaMatcher {
+	return &raceMatcher{
+		description: "all racers to succeed",
+		match: func(outcomes RaceOutcomes) bool {
+			return len(outcomes.Succeeded()) == len(outcomes)
+		},
+	}
+}
+
+// HaveSuccesses succeeds when exactly count racers did not get an error.
+func HaveSuccesses(count int) types.GomegaMatcher {
+	return &raceMatcher{
+		description: fmt.Sprintf("exactly %d racer(s) to succeed", count),
+		match: func(outcomes RaceOutcomes) bool {
+			return len(outcomes.Succeeded()) == count
+		},
+	}
+}
+
+// HaveAtLeastSuccesses succeeds when count or more racers did not get an
+// error.
+func HaveAtLeastSuccesses(count int) types.GomegaMatcher {
+	return &raceMatcher{
+		description: fmt.Sprintf("at least %d racer(s) to succeed", count),
+		match: func(outcomes RaceOutcomes) bool {
+			return len(outcomes.Succeeded()) >= count
+		},
+	}
+}
+
+// HaveIdenticalValues succeeds when at least one racer succeeded and every
+// racer that succeeded got the same credential value back.
+func HaveIdenticalValues() types.GomegaMatcher {
+	return &raceMatcher{
+		description: "the racers that succeeded to get identical values",
+		match: func(outcomes RaceOutcomes) bool {
+			values := outcomes.Values()
+			for _, value := range values {
+				if value != values[0] {
+					return false
+				}
+			}
+			return len(values) > 0
+		},
+	}
+}
+
+// HaveFailureContaining succeeds when at least one racer got an error
+// containing message.
+func HaveFailureContaining(message string) types.GomegaMatcher {
+	return &raceMatcher{
+		description: fmt.Sprintf("at least one racer to fail with %q", message),
+		match: func(outcomes RaceOutcomes) bool {
+			for _, outcome := range outcomes {
+				if outcome.Err != nil && strings.Contains(outcome.Err.Error(), message) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// OnlyFailWith succeeds when every racer that got an error got one containing message.
+func OnlyFailWith(message string) types.GomegaMatcher {
+	return &raceMatcher{
+		description: fmt.Sprintf("every racer that failed to fail with %q", message),
+		match: func(outcomes RaceOutcomes) bool {
+			for _, outcome := range outcomes {
+				if outcome.Err != nil && !strings.Contains(outcome.Err.Error(), message) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+type raceMatcher struct {
+	description string
+	match       func(outcomes RaceOutcomes) bool
+}
+
+func (m *raceMatcher) Match(actual interface{}) (bool, error) {
+	outcomes, ok := actual.(RaceOutcomes)
+	if !ok {
+		return false, fmt.Errorf("Race matcher expects RaceOutcomes.  Got:\n%s", format.Object(actual, 1))
+	}
+	return m.match(outcomes), nil
+}
+
+func (m *raceMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected %s, but got\n%s", m.description, describeOutcomes(actual.(RaceOutcomes)))
+}
+
+func (m *raceMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected not %s, but got\n%s", m.description, describeOutcomes(actual.(RaceOutcomes)))
+}
+
+func describeOutcomes(outcomes RaceOutcomes) string {
+	var out bytes.Buffer
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			fmt.Fprintf(&out, "    racer %d failed: %s\n", outcome.Racer, outcome.Err)
+			continue
+		}
+		value := compactValue(outcome.Credential.Value)
+		if len(value) > 60 {
+			value = value[:60] + "..."
+		}
+		fmt.Fprintf(&out, "    racer %d succeeded: id %s, value %s\n", outcome.Racer, outcome.Credential.Id, value)
+	}
+	return out.String()
+}
+
+func compactValue(value json.RawMessage) string {
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, value) != nil {
+		return string(value)
+	}
+	return compacted.String()
+}
@@ -0,0 +1,120 @@
+package test_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CredentialSnapshot records every version of a set of credentials, to check
+// that a restore brought all of them back unchanged.
+type CredentialSnapshot struct {
+	// Versions are by credential name, newest first.
+	Versions map[string][]Credential
+
+	// CaLinks maps the id of each certificate version to the id of the
+	// snapshotted certificate version whose certificate is its CA.
+	CaLinks map[string]string
+}
+
+// TakeSnapshot gets every version of the named credentials.
+func TakeSnapshot(client *CredhubClient, names ...string) (CredentialSnapshot, error) {
+	snapshot := CredentialSnapshot{Versions: map[string][]Credential{}, CaLinks: map[string]string{}}
+	for _, name := range names {
+		versions, err := client.GetAllVersions(name)
+		if err != nil {
+			return CredentialSnapshot{}, fmt.Errorf("%s: %s", name, err)
+		}
+		snapshot.Versions[name] = versions
+	}
+
+	issuers := map[string]string{}
+	for _, versions := range snapshot.Versions {
+		for _, version := range versions {
+			if version.Type == "certificate" {
+				issuers[version.AsCertificate().Value.Certificate] = version.Id
+			}
+		}
+	}
+	for _, versions := range snapshot.Versions {
+		for _, version := range versions {
+			if version.Type != "certificate" {
+				continue
+			}
+			if issuer, ok := issuers[version.AsCertificate().Value.Ca]; ok {
+				snapshot.CaLinks[version.Id] = issuer
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Diff lists every difference between the snapshot and a later one, one per
+// line, or nothing when they are identical.
+func (s CredentialSnapshot) Diff(later CredentialSnapshot) []string {
+	diff := []string{}
+	for _, name := range sortedKeys(s.Versions, later.Versions) {
+		before, after := s.Versions[name], later.Versions[name]
+		if len(before) != len(after) {
+			diff = append(diff, fmt.Sprintf("%s: %d versions, then %d", name, len(before), len(after)))
+		}
+		for i := 0; i < len(before) && i < len(after); i++ {
+			for _, difference := range diffVersions(before[i], after[i]) {
+				diff = append(diff, fmt.Sprintf("%s version %d: %s", name, i, difference))
+			}
+		}
+	}
+
+	for id, ca := range s.CaLinks {
+		if later.CaLinks[id] != ca {
+			diff = append(diff, fmt.Sprintf("certificate %s: issued by %s, then by %q", id, ca, later.CaLinks[id]))
+		}
+	}
+	for id, ca := range later.CaLinks {
+		if _, ok := s.CaLinks[id]; !ok {
+			diff = append(diff, fmt.Sprintf("certificate %s: no CA, then issued by %s", id, ca))
+		}
+	}
+
+	sort.Strings(diff)
+	return diff
+}
+
+func diffVersions(before, after Credential) []string {
+	diff := []string{}
+	for _, field := range []struct{ name, before, after string }{
+		{"id", before.Id, after.Id},
+		{"type", before.Type, after.Type},
+		{"version_created_at", before.VersionCreatedAt, after.VersionCreatedAt},
+	} {
+		if field.before != field.after {
+			diff = append(diff, fmt.Sprintf("%s %s, then %s", field.name, field.before, field.after))
+		}
+	}
+
+	var beforeValue, afterValue interface{}
+	json.Unmarshal(before.Value, &beforeValue)
+	json.Unmarshal(after.Value, &afterValue)
+	if !reflect.DeepEqual(beforeValue, afterValue) {
+		diff = append(diff, fmt.Sprintf("value %s, then %s", before.Value, after.Value))
+	}
+	return diff
+}
+
+func sortedKeys(maps ...map[string][]Credential) []string {
+	unique := map[string]bool{}
+	for _, m := range maps {
+		for key := range m {
+			unique[key] = true
+		}
+	}
+
+	keys := []string{}
+	for key := range unique {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,174 @@
+// Package sql_injection sends SQL and JPQL injection payloads to the CredHub
+// API and flags responses that suggest one of them reached the database.
+package sql_injection
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// databaseErrors are fragments of errors from the JDBC drivers, Hibernate and
+// the databases themselves, which CredHub should never pass on to clients.
+var databaseErrors = regexp.MustCompile(`SQLException|SQLGrammarException|SQL syntax|syntax error at or near|JDBC|Hibernate|QuerySyntaxException|PSQLException|org\.h2\.|ORA-[0-9]{5}|unterminated quoted string|could not execute (query|statement)`)
+
+// Sender makes raw API requests. CredhubClient implements it.
+type Sender interface {
+	Send(method, apiPath string, body []byte) (int, []byte, error)
+}
+
+type Anomaly struct {
+	Target  string
+	Payload string
+	Reason  string
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s with %q: %s", a.Target, a.Payload, a.Reason)
+}
+
+// Fuzzer sends every payload to a target and checks the responses.
+type Fuzzer struct {
+	Client Sender
+
+	// Root is the path the targets name credentials under.
+	Root string
+
+	// Secrets must not appear in a response unless the request contained
+	// them, e.g. the name and value of a canary credential outside Root.
+	Secrets []string
+
+	// Invariant is checked after every request, e.g. that the canary
+	// credential can still be read.
+	Invariant func() error
+
+	// TimingMargin is how much slower than the baseline a time-based payload
+	// may be answered. It defaults to a second less than SleepDuration.
+	TimingMargin time.Duration
+
+	// Payloads default to the package's Payloads.
+	Payloads []Payload
+}
+
+type response struct {
+	status   int
+	body     []byte
+	request  string
+	duration time.Duration
+}
+
+// Fuzz returns the anomalies found at target. It only fails when a request
+// could not be sent at all.
+func (f *Fuzzer) Fuzz(target Target) ([]Anomaly, error) {
+	payloads := f.Payloads
+	if payloads == nil {
+		payloads = Payloads
+	}
+	margin := f.TimingMargin
+	if margin == 0 {
+		margin = SleepDuration - time.Second
+	}
+
+	var baseline time.Duration
+	for i := 0; i < 3; i++ {
+		benign, err := f.send(target, "sql-injection-baseline")
+		if err != nil {
+			return nil, err
+		}
+		if benign.duration > baseline {
+			baseline = benign.duration
+		}
+	}
+
+	anomalies := []Anomaly{}
+	report := func(payload, reason string, args ...interface{}) {
+		anomalies = append(anomalies, Anomaly{Target: target.Description, Payload: payload, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	for _, payload := range payloads {
+		injected, err := f.send(target, payload.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, reason := range f.inspect(injected) {
+			report(payload.Value, reason)
+		}
+
+		if payload.Kind == TimeBased && injected.duration > baseline+margin {
+			report(payload.Value, "took %s, against %s without it", injected.duration.Round(time.Millisecond), baseline.Round(time.Millisecond))
+		}
+
+		if payload.Negation != "" && target.Differential {
+			negated, err := f.send(target, payload.Negation)
+			if err != nil {
+				return nil, err
+			}
+			for _, reason := range f.inspect(negated) {
+				report(payload.Negation, reason)
+			}
+			if normalize(injected, payload.Value) != normalize(negated, payload.Negation) {
+				report(payload.Value, "got %d %s, but %d %s for %q", injected.status, injected.body, negated.status, negated.body, payload.Negation)
+			}
+		}
+	}
+
+	return anomalies, nil
+}
+
+func (f *Fuzzer) send(target Target, value string) (response, error) {
+	method, apiPath, body := target.Request(f.Root, value)
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return response{}, err
+		}
+	}
+
+	start := time.Now()
+	status, responseBody, err := f.Client.Send(method, apiPath, encoded)
+	if err != nil {
+		return response{}, fmt.Errorf("%s %s: %s", method, apiPath, err)
+	}
+	return response{
+		status:   status,
+		body:     responseBody,
+		request:  apiPath + " " + string(encoded),
+		duration: time.Since(start),
+	}, nil
+}
+
+// inspect returns the reasons a single response looks like an injection.
+func (f *Fuzzer) inspect(r response) []string {
+	reasons := []string{}
+	if r.status >= 500 {
+		reasons = append(reasons, fmt.Sprintf("server error %d: %s", r.status, r.body))
+	}
+	if match := databaseErrors.Find(r.body); match != nil {
+		reasons = append(reasons, fmt.Sprintf("database error %q in %s", match, r.body))
+	}
+	for _, secret := range f.Secrets {
+		if strings.Contains(string(r.body), secret) && !strings.Contains(r.request, secret) {
+			reasons = append(reasons, fmt.Sprintf("leaked %q: %s", secret, r.body))
+		}
+	}
+	if f.Invariant != nil {
+		if err := f.Invariant(); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+	}
+	return reasons
+}
+
+// normalize removes the payload, as sent and as echoed in JSON, from a
+// response so that responses to different payloads can be compared.
+func normalize(r response, payload string) string {
+	body := strings.Replace(string(r.body), payload, "<payload>", -1)
+	if escaped, err := json.Marshal(payload); err == nil {
+		body = strings.Replace(body, strings.Trim(string(escaped), `"`), "<payload>", -1)
+	}
+	return fmt.Sprintf("%d %s", r.status, body)
+}
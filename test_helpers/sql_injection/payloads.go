@@ -0,0 +1,61 @@
+package sql_injection
+
+import "time"
+
+// SleepDuration is how long the time-based payloads ask the database to sleep.
+const SleepDuration = 5 * time.Second
+
+type Kind string
+
+const (
+	Boolean      Kind = "boolean"
+	TimeBased    Kind = "time-based"
+	Union        Kind = "union"
+	Stacked      Kind = "stacked"
+	LikeWildcard Kind = "like-wildcard"
+)
+
+type Payload struct {
+	Kind  Kind
+	Value string
+
+	// Negation is a payload that differs from Value only in making the
+	// injected condition false. A server that returns something different for
+	// the two evaluated the condition.
+	Negation string
+}
+
+// Payloads are written for the databases CredHub supports (PostgreSQL, MySQL
+// and H2) and for JPQL/HQL, which CredHub's repositories query through. None
+// of them change data if they are executed.
+var Payloads = []Payload{
+	{Kind: Boolean, Value: `' OR '1'='1`, Negation: `' AND '1'='2`},
+	{Kind: Boolean, Value: `' OR 1=1 -- `, Negation: `' AND 1=2 -- `},
+	{Kind: Boolean, Value: `') OR ('1'='1`, Negation: `') AND ('1'='2`},
+	{Kind: Boolean, Value: `" OR "1"="1`, Negation: `" AND "1"="2`},
+	{Kind: Boolean, Value: `' OR 'a' LIKE 'a`, Negation: `' AND 'a' LIKE 'b`},
+	{Kind: Boolean, Value: `' OR TRUE OR '`, Negation: `' AND FALSE AND '`},
+
+	{Kind: TimeBased, Value: `'||pg_sleep(5)||'`},
+	{Kind: TimeBased, Value: `'; SELECT pg_sleep(5); -- `},
+	{Kind: TimeBased, Value: `' AND (SELECT 1 FROM (SELECT SLEEP(5)) AS x) -- `},
+	{Kind: TimeBased, Value: `' OR SLEEP(5) -- `},
+	{Kind: TimeBased, Value: `'; WAITFOR DELAY '0:0:5'; -- `},
+	{Kind: TimeBased, Value: `' AND 1=(SELECT COUNT(*) FROM SYSTEM_RANGE(1, 50000000)) -- `},
+
+	{Kind: Union, Value: `' UNION SELECT NULL -- `},
+	{Kind: Union, Value: `' UNION SELECT NULL, NULL, NULL, NULL -- `},
+	{Kind: Union, Value: `' UNION ALL SELECT table_name FROM information_schema.tables -- `},
+	{Kind: Union, Value: `' UNION SELECT c FROM CredentialVersion c WHERE '1'='1`},
+
+	{Kind: Stacked, Value: `'; SELECT 1; -- `},
+	{Kind: Stacked, Value: `'); SELECT 1; -- `},
+	{Kind: Stacked, Value: `'; COMMIT; -- `},
+
+	{Kind: LikeWildcard, Value: `%`},
+	{Kind: LikeWildcard, Value: `_`},
+	{Kind: LikeWildcard, Value: `%%`},
+	{Kind: LikeWildcard, Value: `\%`},
+	{Kind: LikeWildcard, Value: `[a-z]%`},
+	{Kind: LikeWildcard, Value: `%' ESCAPE '\`},
+}
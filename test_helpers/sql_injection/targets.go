@@ -0,0 +1,138 @@
+package sql_injection
+
+import "net/url"
+
+// Target is one place in the API a payload can be put.
+type Target struct {
+	Description string
+
+	// Request puts value in the target's field. Credentials it names are
+	// under root.
+	Request func(root, value string) (method, apiPath string, body interface{})
+
+	// Differential targets answer the same to a payload and its negation
+	// unless the payload was evaluated. Writes do not, as every write creates
+	// a new version with its own id and timestamp.
+	Differential bool
+}
+
+// Targets cover every field of the data and interpolate endpoints that is
+// used to look up or store credentials.
+var Targets = []Target{
+	{
+		Description:  "GET /api/v1/data?name=",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "GET", "/api/v1/data?name=" + url.QueryEscape(root+"/"+value), nil
+		},
+	},
+	{
+		Description:  "GET /api/v1/data?name=&current=true",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "GET", "/api/v1/data?current=true&name=" + url.QueryEscape(root+"/"+value), nil
+		},
+	},
+	{
+		Description:  "GET /api/v1/data?path=",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "GET", "/api/v1/data?path=" + url.QueryEscape(root+"/"+value), nil
+		},
+	},
+	{
+		Description:  "GET /api/v1/data?name-like=",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "GET", "/api/v1/data?name-like=" + url.QueryEscape(value), nil
+		},
+	},
+	{
+		Description:  "GET /api/v1/data/:id",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "GET", "/api/v1/data/" + url.PathEscape(value), nil
+		},
+	},
+	{
+		Description: "PUT /api/v1/data name",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "PUT", "/api/v1/data", map[string]interface{}{
+				"name": root + "/" + value, "type": "password", "value": "fuzz-value", "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "PUT /api/v1/data type",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "PUT", "/api/v1/data", map[string]interface{}{
+				"name": root + "/set-type", "type": value, "value": "fuzz-value", "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "PUT /api/v1/data value",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "PUT", "/api/v1/data", map[string]interface{}{
+				"name": root + "/set-value", "type": "value", "value": value, "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "PUT /api/v1/data JSON value",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "PUT", "/api/v1/data", map[string]interface{}{
+				"name": root + "/set-json", "type": "json", "value": map[string]string{value: value}, "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "POST /api/v1/data name",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "POST", "/api/v1/data", map[string]interface{}{
+				"name": root + "/" + value, "type": "password", "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "POST /api/v1/data type",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "POST", "/api/v1/data", map[string]interface{}{
+				"name": root + "/generate-type", "type": value, "overwrite": true,
+			}
+		},
+	},
+	{
+		Description: "POST /api/v1/data parameters",
+		Request: func(root, value string) (string, string, interface{}) {
+			return "POST", "/api/v1/data", map[string]interface{}{
+				"name": root + "/generate-user", "type": "user", "parameters": map[string]string{"username": value}, "overwrite": true,
+			}
+		},
+	},
+	{
+		Description:  "POST /api/v1/data regenerate name",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "POST", "/api/v1/data", map[string]interface{}{"name": root + "/" + value, "regenerate": true}
+		},
+	},
+	{
+		Description:  "DELETE /api/v1/data?name=",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "DELETE", "/api/v1/data?name=" + url.QueryEscape(root+"/"+value), nil
+		},
+	},
+	{
+		Description:  "POST /api/v1/interpolate credhub-ref",
+		Differential: true,
+		Request: func(root, value string) (string, string, interface{}) {
+			return "POST", "/api/v1/interpolate", map[string]interface{}{
+				"p-config-server": []interface{}{
+					map[string]interface{}{"credentials": map[string]string{"credhub-ref": "((" + root + "/" + value + "))"}},
+				},
+			}
+		},
+	},
+}
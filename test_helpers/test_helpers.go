@@ -1,13 +1,9 @@
 package test_helpers
 
 import (
-	"encoding/json"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
-	"strconv"
-	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -18,10 +14,6 @@ var (
 	CommandPath string
 )
 
-func GenerateUniqueCredentialName() string {
-	return strconv.FormatInt(time.Now().UnixNano(), 10)
-}
-
 func RunCommand(args ...string) *Session {
 	cmd := exec.Command(CommandPath, args...)
 
@@ -29,40 +21,11 @@ func RunCommand(args ...string) *Session {
 	Expect(err).NotTo(HaveOccurred())
 	<-session.Exited
 
-	return session
-}
-
-type BoshConfig struct {
-	Host              string `json:"host"`
-	SshUsername       string `json:"bosh_ssh_username"`
-	SshPrivateKeyPath string `json:"bosh_ssh_private_key_path"`
-}
-
-type Config struct {
-	Bosh           *BoshConfig `json:"bosh"`
-	ApiUrl         string      `json:"api_url"`
-	ApiUsername    string      `json:"api_username"`
-	ApiPassword    string      `json:"api_password"`
-	CredentialRoot string      `json:"credential_root"`
-	UAACa	       string	   `json:"uaa_ca"`
-	DirectorHost   string      `json:"director_host"`
-}
-
-func LoadConfig() (Config, error) {
-
-	configuration := Config{}
-
-	configurationJson, err := ioutil.ReadFile(path.Join(os.Getenv("PWD"), "test_config.json"))
-	if err != nil {
-		return configuration, err
+	if session.ExitCode() == 0 {
+		trackCommand(args)
 	}
 
-	err = json.Unmarshal(configurationJson, &configuration)
-	if err != nil {
-		return configuration, err
-	}
-
-	return configuration, nil
+	return session
 }
 
 func TargetAndLogin(cfg Config) {
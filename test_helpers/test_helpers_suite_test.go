@@ -0,0 +1,30 @@
+package test_helpers_test
+
+import (
+	"testing"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTestHelpers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, SuiteDescription("Test Helpers Suite"))
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+})
+
+var _ = AfterEach(func() {
+	CleanupCredentials()
+})
+
+var _ = SynchronizedAfterSuite(func() {
+	ReportCleanup()
+}, func() {
+	StopSuite()
+})
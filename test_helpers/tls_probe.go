@@ -0,0 +1,379 @@
+package test_helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"text/tabwriter"
+	"time"
+)
+
+// TLSVersions are the protocol versions a TLSProbe tries, oldest first.
+var TLSVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// TLSProbe handshakes with a server in different ways to find out how its TLS
+// is configured.
+type TLSProbe struct {
+	Address    string
+	ServerName string
+	RootCAs    *x509.CertPool
+	Timeout    time.Duration
+}
+
+// TLSReport is what a TLSProbe found out.
+type TLSReport struct {
+	// AcceptedCipherSuites has the cipher suites accepted with each protocol
+	// version, or nil for versions the server refused. The TLS 1.3 suites
+	// cannot be offered one at a time, so only the negotiated one is listed.
+	AcceptedCipherSuites map[uint16][]uint16
+
+	// ServerCipherPreference is set when the server chose the same cipher
+	// suite whatever order it was offered in. It is only meaningful when
+	// CipherPreferenceTested is set, which needs two accepted TLS 1.2 suites.
+	ServerCipherPreference bool
+	CipherPreferenceTested bool
+
+	// ChainError is why the presented chain did not verify against the
+	// trusted CAs, or nil.
+	ChainError error
+}
+
+// NewTLSProbe probes the server at cfg.ApiUrl, trusting server_ca_cert.pem
+// from the credential root.
+func NewTLSProbe(cfg Config) (*TLSProbe, error) {
+	apiUrl, err := url.Parse(cfg.ApiUrl)
+	if err != nil {
+		return nil, err
+	}
+	address := apiUrl.Host
+	if apiUrl.Port() == "" {
+		address = net.JoinHostPort(apiUrl.Hostname(), "443")
+	}
+
+	rootCAs, err := loadTrustedCAs(path.Join(cfg.CredentialRoot, "server_ca_cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSProbe{
+		Address:    address,
+		ServerName: apiUrl.Hostname(),
+		RootCAs:    rootCAs,
+		Timeout:    10 * time.Second,
+	}, nil
+}
+
+// Report runs every probe. It only fails when the server cannot be reached at
+// all; refused handshakes are part of the report.
+func (p *TLSProbe) Report() (TLSReport, error) {
+	report := TLSReport{AcceptedCipherSuites: map[uint16][]uint16{}}
+
+	for _, version := range TLSVersions {
+		accepted, err := p.acceptedCipherSuites(version)
+		if err != nil {
+			return TLSReport{}, err
+		}
+		report.AcceptedCipherSuites[version] = accepted
+	}
+
+	tls12 := report.AcceptedCipherSuites[tls.VersionTLS12]
+	if len(tls12) >= 2 {
+		preference, err := p.serverChoosesSuite(tls12[0], tls12[1])
+		if err != nil {
+			return TLSReport{}, err
+		}
+		report.CipherPreferenceTested = true
+		report.ServerCipherPreference = preference
+	}
+
+	_, handshakeErr, err := p.handshake(&tls.Config{RootCAs: p.RootCAs, ServerName: p.ServerName})
+	if err != nil {
+		return TLSReport{}, err
+	}
+	report.ChainError = handshakeErr
+
+	return report, nil
+}
+
+// serverChoosesSuite offers two cipher suites in both orders and reports
+// whether the server chose the same one both times.
+func (p *TLSProbe) serverChoosesSuite(a, b uint16) (bool, error) {
+	chosen := map[uint16]bool{}
+	for _, order := range [][]uint16{{a, b}, {b, a}} {
+		suite, accepted, err := p.chooseCipherSuite(tls.VersionTLS12, order)
+		if err != nil || !accepted {
+			return false, err
+		}
+		chosen[suite] = true
+	}
+	return len(chosen) == 1, nil
+}
+
+// chooseCipherSuite sends a ClientHello for version offering suites in the
+// given order and returns the suite the server picks. crypto/tls always offers
+// suites in its own order, and only the ones it implements, so the hello is
+// written by hand.
+func (p *TLSProbe) chooseCipherSuite(version uint16, suites []uint16) (uint16, bool, error) {
+	conn, err := net.DialTimeout("tcp", p.Address, p.Timeout)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+
+	if _, err := conn.Write(clientHello(p.ServerName, version, suites)); err != nil {
+		return 0, false, nil
+	}
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, false, nil
+	}
+	const recordTypeHandshake, handshakeTypeServerHello = 22, 2
+	body := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+	if _, err := io.ReadFull(conn, body); err != nil || header[0] != recordTypeHandshake || len(body) < 39 || body[0] != handshakeTypeServerHello {
+		return 0, false, nil
+	}
+	if binary.BigEndian.Uint16(body[4:6]) != version {
+		return 0, false, nil
+	}
+
+	// type (1), length (3), version (2), random (32), session ID
+	sessionIdEnd := 39 + int(body[38])
+	if len(body) < sessionIdEnd+2 {
+		return 0, false, nil
+	}
+	return binary.BigEndian.Uint16(body[sessionIdEnd:]), true, nil
+}
+
+func clientHello(serverName string, version uint16, suites []uint16) []byte {
+	var extensions bytes.Buffer
+	if serverName != "" && net.ParseIP(serverName) == nil {
+		writeExtension(&extensions, 0x0000, lengthPrefixed(2, append([]byte{0}, lengthPrefixed(2, []byte(serverName))...)))
+	}
+	// x25519, secp256r1, secp384r1
+	writeExtension(&extensions, 0x000a, lengthPrefixed(2, []byte{0x00, 0x1d, 0x00, 0x17, 0x00, 0x18}))
+	// uncompressed points
+	writeExtension(&extensions, 0x000b, []byte{1, 0})
+	// RSA-PSS, RSA PKCS #1 v1.5 and ECDSA with SHA-256, SHA-384 and SHA-512,
+	// which only TLS 1.2 negotiates
+	if version >= tls.VersionTLS12 {
+		writeExtension(&extensions, 0x000d, lengthPrefixed(2, []byte{
+			0x08, 0x04, 0x08, 0x05, 0x08, 0x06,
+			0x04, 0x01, 0x05, 0x01, 0x06, 0x01,
+			0x04, 0x03, 0x05, 0x03, 0x06, 0x03,
+		}))
+	}
+	// secure renegotiation
+	writeExtension(&extensions, 0xff01, []byte{0})
+
+	var hello bytes.Buffer
+	binary.Write(&hello, binary.BigEndian, version)
+	random := make([]byte, 32)
+	rand.Read(random)
+	hello.Write(random)
+	hello.WriteByte(0)
+	cipherSuites := make([]byte, 2*len(suites))
+	for i, suite := range suites {
+		binary.BigEndian.PutUint16(cipherSuites[2*i:], suite)
+	}
+	hello.Write(lengthPrefixed(2, cipherSuites))
+	hello.Write([]byte{1, 0})
+	hello.Write(lengthPrefixed(2, extensions.Bytes()))
+
+	handshake := append([]byte{1}, lengthPrefixed(3, hello.Bytes())...)
+	return append([]byte{22, 0x03, 0x01}, lengthPrefixed(2, handshake)...)
+}
+
+func writeExtension(extensions *bytes.Buffer, extensionType uint16, data []byte) {
+	binary.Write(extensions, binary.BigEndian, extensionType)
+	extensions.Write(lengthPrefixed(2, data))
+}
+
+func lengthPrefixed(lengthBytes int, data []byte) []byte {
+	length := len(data)
+	prefixed := make([]byte, lengthBytes, lengthBytes+length)
+	for i := lengthBytes - 1; i >= 0; i-- {
+		prefixed[i] = byte(length)
+		length >>= 8
+	}
+	return append(prefixed, data...)
+}
+
+func (p *TLSProbe) acceptedCipherSuites(version uint16) ([]uint16, error) {
+	if version == tls.VersionTLS13 {
+		state, _, err := p.handshake(&tls.Config{MinVersion: version, MaxVersion: version})
+		if err != nil || state == nil {
+			return nil, err
+		}
+		return []uint16{state.CipherSuite}, nil
+	}
+
+	var accepted []uint16
+	for _, suite := range cipherSuitesFor(version) {
+		state, _, err := p.handshake(&tls.Config{MinVersion: version, MaxVersion: version, CipherSuites: []uint16{suite}})
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			accepted = append(accepted, suite)
+		}
+	}
+	for _, suite := range legacyCipherSuites {
+		chosen, ok, err := p.chooseCipherSuite(version, []uint16{suite.ID})
+		if err != nil {
+			return nil, err
+		}
+		if ok && chosen == suite.ID {
+			accepted = append(accepted, suite.ID)
+		}
+	}
+	return accepted, nil
+}
+
+// handshake returns the connection state when the handshake succeeded, and
+// handshakeErr when it did not. err is only set when the server could not be
+// reached.
+func (p *TLSProbe) handshake(config *tls.Config) (state *tls.ConnectionState, handshakeErr error, err error) {
+	if config.RootCAs == nil {
+		config.InsecureSkipVerify = true
+	}
+
+	conn, err := net.DialTimeout("tcp", p.Address, p.Timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.Timeout))
+
+	client := tls.Client(conn, config)
+	if handshakeErr := client.Handshake(); handshakeErr != nil {
+		return nil, handshakeErr, nil
+	}
+	connectionState := client.ConnectionState()
+	return &connectionState, nil, nil
+}
+
+func cipherSuitesFor(version uint16) []uint16 {
+	var suites []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, supported := range suite.SupportedVersions {
+			if supported == version && version != tls.VersionTLS13 {
+				suites = append(suites, suite.ID)
+				break
+			}
+		}
+	}
+	return suites
+}
+
+// legacyCipherSuites are suites crypto/tls does not implement at all, so they
+// are offered with a hand-written ClientHello: DHE key exchange, NULL
+// encryption, EXPORT grade and anonymous suites. All of them are insecure.
+var legacyCipherSuites = []struct {
+	ID   uint16
+	Name string
+}{
+	{0x0016, "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA"},
+	{0x0032, "TLS_DHE_DSS_WITH_AES_128_CBC_SHA"},
+	{0x0033, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA"},
+	{0x0038, "TLS_DHE_DSS_WITH_AES_256_CBC_SHA"},
+	{0x0039, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA"},
+	{0x0067, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256"},
+	{0x006b, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256"},
+	{0x009e, "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256"},
+	{0x009f, "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384"},
+	{0xccaa, "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256"},
+
+	{0x0001, "TLS_RSA_WITH_NULL_MD5"},
+	{0x0002, "TLS_RSA_WITH_NULL_SHA"},
+	{0x003b, "TLS_RSA_WITH_NULL_SHA256"},
+	{0xc006, "TLS_ECDHE_ECDSA_WITH_NULL_SHA"},
+	{0xc010, "TLS_ECDHE_RSA_WITH_NULL_SHA"},
+
+	{0x0003, "TLS_RSA_EXPORT_WITH_RC4_40_MD5"},
+	{0x0006, "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5"},
+	{0x0008, "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA"},
+	{0x0014, "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA"},
+	{0x0017, "TLS_DH_anon_EXPORT_WITH_RC4_40_MD5"},
+
+	{0x0018, "TLS_DH_anon_WITH_RC4_128_MD5"},
+	{0x001b, "TLS_DH_anon_WITH_3DES_EDE_CBC_SHA"},
+	{0x0034, "TLS_DH_anon_WITH_AES_128_CBC_SHA"},
+	{0x003a, "TLS_DH_anon_WITH_AES_256_CBC_SHA"},
+	{0x00a6, "TLS_DH_anon_WITH_AES_128_GCM_SHA256"},
+	{0xc018, "TLS_ECDH_anon_WITH_AES_128_CBC_SHA"},
+	{0xc019, "TLS_ECDH_anon_WITH_AES_256_CBC_SHA"},
+}
+
+// IsInsecureCipherSuite reports whether the suite is insecure: either Go
+// considers it so, e.g. because it uses RC4, 3DES or CBC with SHA-256, or Go
+// does not implement it at all.
+func IsInsecureCipherSuite(suite uint16) bool {
+	for _, insecure := range tls.InsecureCipherSuites() {
+		if insecure.ID == suite {
+			return true
+		}
+	}
+	for _, legacy := range legacyCipherSuites {
+		if legacy.ID == suite {
+			return true
+		}
+	}
+	return false
+}
+
+// CipherSuiteName is tls.CipherSuiteName, also naming the suites Go does not
+// implement.
+func CipherSuiteName(suite uint16) string {
+	for _, legacy := range legacyCipherSuites {
+		if legacy.ID == suite {
+			return legacy.Name
+		}
+	}
+	return tls.CipherSuiteName(suite)
+}
+
+// Table lists the accepted protocol versions and cipher suites.
+func (r TLSReport) Table() string {
+	var out bytes.Buffer
+	table := tabwriter.NewWriter(&out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(table, "PROTOCOL\tCIPHER SUITE\tINSECURE")
+	for _, version := range TLSVersions {
+		accepted := r.AcceptedCipherSuites[version]
+		if len(accepted) == 0 {
+			fmt.Fprintf(table, "%s\t(refused)\t\n", tls.VersionName(version))
+			continue
+		}
+		for _, suite := range accepted {
+			insecure := ""
+			if IsInsecureCipherSuite(suite) {
+				insecure = "yes"
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\n", tls.VersionName(version), CipherSuiteName(suite), insecure)
+		}
+	}
+	table.Flush()
+
+	preference := "client"
+	if !r.CipherPreferenceTested {
+		preference = "not tested"
+	} else if r.ServerCipherPreference {
+		preference = "server"
+	}
+	chain := "verified"
+	if r.ChainError != nil {
+		chain = r.ChainError.Error()
+	}
+	fmt.Fprintf(&out, "cipher preference: %s\nserver chain: %s\n", preference, chain)
+
+	return out.String()
+}
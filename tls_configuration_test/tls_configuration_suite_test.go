@@ -0,0 +1,40 @@
+package tls_configuration_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var report TLSReport
+
+func TestTlsConfiguration(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, SuiteDescription("TLS Configuration Suite"))
+}
+
+var _ = SynchronizedBeforeSuite(func() []byte {
+	return StartSuite("")
+}, func(data []byte) {
+	JoinSuite(data)
+
+	cfg, err := LoadConfig(TlsConfig...)
+	Expect(err).NotTo(HaveOccurred())
+	probe, err := NewTLSProbe(cfg)
+	Expect(err).NotTo(HaveOccurred())
+
+	report, err = probe.Report()
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = SynchronizedAfterSuite(func() {
+}, func() {
+	if report.AcceptedCipherSuites != nil {
+		fmt.Printf("\nNegotiated TLS configuration:\n%s", report.Table())
+	}
+
+	StopSuite()
+})
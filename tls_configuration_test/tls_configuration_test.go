@@ -0,0 +1,46 @@
+package tls_configuration_test
+
+import (
+	"crypto/tls"
+
+	. "github.com/cloudfoundry-incubator/credhub-acceptance-tests/test_helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS configuration", func() {
+	It("accepts TLS 1.2", func() {
+		Expect(report.AcceptedCipherSuites[tls.VersionTLS12]).NotTo(BeEmpty())
+	})
+
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11} {
+		version := version
+
+		It("refuses "+tls.VersionName(version), func() {
+			Expect(report.AcceptedCipherSuites[version]).To(BeEmpty())
+		})
+	}
+
+	It("does not accept insecure cipher suites", func() {
+		insecure := []string{}
+		for version, suites := range report.AcceptedCipherSuites {
+			for _, suite := range suites {
+				if IsInsecureCipherSuite(suite) {
+					insecure = append(insecure, tls.VersionName(version)+" "+CipherSuiteName(suite))
+				}
+			}
+		}
+		Expect(insecure).To(BeEmpty())
+	})
+
+	It("chooses the cipher suite itself rather than following the client's preference", func() {
+		if !report.CipherPreferenceTested {
+			Skip("the server accepts fewer than two TLS 1.2 cipher suites")
+		}
+		Expect(report.ServerCipherPreference).To(BeTrue())
+	})
+
+	It("presents a certificate chain that verifies against server_ca_cert.pem", func() {
+		Expect(report.ChainError).NotTo(HaveOccurred())
+	})
+})